@@ -28,6 +28,18 @@ var codeJSON []byte
 var codeStruct codeResponse
 
 func decodeResponse(response *codeResponse, r *Reader) error {
+	return decodeResponseWith(response, r, (*Reader).NextName)
+}
+
+// As decodeResponse, but reading names with NextNameBytes.
+func decodeResponseBytes(response *codeResponse, r *Reader) error {
+	return decodeResponseWith(response, r, func(r *Reader) (string, error) {
+		name, err := r.NextNameBytes()
+		return string(name), err
+	})
+}
+
+func decodeResponseWith(response *codeResponse, r *Reader, nextName func(*Reader) (string, error)) error {
 	if err := r.BeginObject(); err != nil {
 		return err
 	}
@@ -37,14 +49,14 @@ func decodeResponse(response *codeResponse, r *Reader) error {
 		} else if !hasNext {
 			break
 		}
-		name, err := r.NextName()
+		name, err := nextName(r)
 		if err != nil {
 			return err
 		}
 		switch name {
 		case "tree":
 			response.Tree = &codeNode{}
-			if err := decodeNode(response.Tree, r); err != nil {
+			if err := decodeNodeWith(response.Tree, r, nextName); err != nil {
 				return err
 			}
 		case "username":
@@ -64,7 +76,7 @@ func decodeResponse(response *codeResponse, r *Reader) error {
 	return nil
 }
 
-func decodeNode(node *codeNode, r *Reader) error {
+func decodeNodeWith(node *codeNode, r *Reader, nextName func(*Reader) (string, error)) error {
 	if err := r.BeginObject(); err != nil {
 		return err
 	}
@@ -74,7 +86,7 @@ func decodeNode(node *codeNode, r *Reader) error {
 		} else if !hasNext {
 			break
 		}
-		name, err := r.NextName()
+		name, err := nextName(r)
 		if err != nil {
 			return err
 		}
@@ -95,7 +107,7 @@ func decodeNode(node *codeNode, r *Reader) error {
 					break
 				}
 				kid := &codeNode{}
-				if err := decodeNode(kid, r); err != nil {
+				if err := decodeNodeWith(kid, r, nextName); err != nil {
 					return err
 				}
 				node.Kids = append(node.Kids, kid)
@@ -308,6 +320,7 @@ func BenchmarkCodeEncoderRgo(b *testing.B) {
 	}
 	w := NewWriter(ioutil.Discard)
 	for i := 0; i < b.N; i++ {
+		w.Reset(ioutil.Discard)
 		if err := encodeResponse(&codeStruct, w); err != nil {
 			b.Fatal("encodeResponse:", err)
 		}
@@ -359,6 +372,64 @@ func BenchmarkCodeDecoderRgo(b *testing.B) {
 	b.SetBytes(int64(len(codeJSON)))
 }
 
+func BenchmarkCodeDecoderBytesRgo(b *testing.B) {
+	if codeJSON == nil {
+		b.StopTimer()
+		codeInit()
+		b.StartTimer()
+	}
+	var response codeResponse
+	for i := 0; i < b.N; i++ {
+		if err := decodeResponseBytes(&response, NewBytesReader(codeJSON)); err != nil {
+			b.Fatal("decodeResponseBytes:", err)
+		}
+	}
+	b.SetBytes(int64(len(codeJSON)))
+}
+
+func BenchmarkCodeSkipJson(b *testing.B) {
+	if codeJSON == nil {
+		b.StopTimer()
+		codeInit()
+		b.StartTimer()
+	}
+	var buf bytes.Buffer
+	dec := json.NewDecoder(&buf)
+	var raw json.RawMessage
+	for i := 0; i < b.N; i++ {
+		buf.Write(codeJSON)
+		if err := dec.Decode(&raw); err != nil {
+			b.Fatal("Decode:", err)
+		}
+	}
+	b.SetBytes(int64(len(codeJSON)))
+}
+
+func BenchmarkCodeSkipRgo(b *testing.B) {
+	benchmarkCodeSkip(b, (*Reader).SkipValue)
+}
+
+func BenchmarkCodeSkipUncheckedRgo(b *testing.B) {
+	benchmarkCodeSkip(b, (*Reader).SkipValueUnchecked)
+}
+
+func benchmarkCodeSkip(b *testing.B, skip func(*Reader) error) {
+	if codeJSON == nil {
+		b.StopTimer()
+		codeInit()
+		b.StartTimer()
+	}
+	var buf bytes.Buffer
+	r := NewReader(&buf)
+	for i := 0; i < b.N; i++ {
+		buf.Write(codeJSON)
+		if err := skip(r); err != nil {
+			b.Fatal("skip:", err)
+		}
+	}
+	b.SetBytes(int64(len(codeJSON)))
+}
+
 func BenchmarkUnmarshalStringJson(b *testing.B) {
 	data := []byte(`"hello, world"`)
 	var s string
@@ -378,6 +449,19 @@ func BenchmarkUnmarshalStringRgo(b *testing.B) {
 	}
 }
 
+func BenchmarkUnmarshalStringResetRgo(b *testing.B) {
+	data := []byte(`"hello, world"`)
+	input := bytes.NewReader(data)
+	r := NewReader(input)
+	for i := 0; i < b.N; i++ {
+		input.Reset(data)
+		r.Reset(input)
+		if _, err := r.NextString(); err != nil {
+			b.Fatal("NextString:", err)
+		}
+	}
+}
+
 func BenchmarkUnmarshalFloat64Json(b *testing.B) {
 	data := []byte(`3.14`)
 	var f float64
@@ -0,0 +1,514 @@
+package rgo
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// A TypeError describes a value that cannot be decoded into, or encoded
+// from, a Go type.
+type TypeError struct {
+	// The JSONPath of the value.
+	Path string
+	// The token that starts the value, or NO_TOKEN when encoding.
+	Token Token
+	Type  reflect.Type
+}
+
+func (e *TypeError) Error() string {
+	if e.Token == NO_TOKEN {
+		return "rgo: Cannot encode " + e.Type.String() + " at " + e.Path
+	}
+	return fmt.Sprintf("rgo: Cannot decode %s into %s at %s", tokenName(e.Token), e.Type, e.Path)
+}
+
+func tokenName(token Token) string {
+	switch token {
+	case BEGIN_ARRAY:
+		return "array"
+	case BEGIN_OBJECT:
+		return "object"
+	case BOOLEAN:
+		return "boolean"
+	case NULL:
+		return "null"
+	case NUMBER:
+		return "number"
+	case STRING:
+		return "string"
+	default:
+		return "token " + strconv.Itoa(int(token))
+	}
+}
+
+var (
+	numberType = reflect.TypeOf(Number(""))
+	valueType  = reflect.TypeOf((*Value)(nil)).Elem()
+)
+
+// Read the next value into v, which must be a non-nil pointer, as
+// encoding/json's Unmarshal would, following the json tags of struct
+// fields.  Objects may be decoded into structs and into maps with string
+// or integer keys, and arrays into slices and arrays.  A string is decoded
+// into a []byte as base64.  Numbers are decoded into an interface{} as a
+// Number, keeping their exact text, and any value may be decoded into a
+// Value.  Only the next value is read, so this may be called at any point
+// in a stream, such as for each element of a large array in turn.  If a
+// value cannot be decoded into its Go type, such as a string into an int
+// or a number that is out of range, it is skipped, the rest of the value
+// is decoded, and then the first such error, such as a TypeError, is
+// returned.  If Limits.MaxDepth is not set, values nested more than 10000
+// deep return a DepthLimitError.
+func Decode(r *Reader, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return IllegalArgument
+	}
+	return decodeValue(r, rv.Elem())
+}
+
+func decodeValue(r *Reader, v reflect.Value) error {
+	token, err := r.Peek()
+	if err != nil {
+		return err
+	}
+	switch token {
+	case END_ARRAY, END_OBJECT, END_DOCUMENT, NAME:
+		return IllegalState
+	case BEGIN_ARRAY, BEGIN_OBJECT:
+		if err := r.checkRecursionDepth(); err != nil {
+			return err
+		}
+	}
+	if v.Type() == valueType {
+		value, err := ReadValue(r)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(&value).Elem())
+		return nil
+	}
+	if token == NULL {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return r.NextNull()
+	}
+	if v.Type() == numberType {
+		if token != NUMBER {
+			return typeError(r, token, v.Type())
+		}
+		n, err := r.NextNumber()
+		v.SetString(string(n))
+		return err
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeValue(r, v.Elem())
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return typeError(r, token, v.Type())
+		}
+		value, err := decodeInterface(r)
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(value))
+		}
+		return nil
+	case reflect.Bool:
+		if token != BOOLEAN {
+			return typeError(r, token, v.Type())
+		}
+		b, err := r.NextBoolean()
+		v.SetBool(b)
+		return err
+	case reflect.String:
+		if token != STRING {
+			return typeError(r, token, v.Type())
+		}
+		s, err := r.NextString()
+		v.SetString(s)
+		return err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if token != NUMBER {
+			return typeError(r, token, v.Type())
+		}
+		i, err := r.nextInt(v.Type().Bits(), v.Type().String())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if token != NUMBER {
+			return typeError(r, token, v.Type())
+		}
+		u, err := r.nextUint(v.Type().Bits(), v.Type().String())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		if token != NUMBER {
+			return typeError(r, token, v.Type())
+		}
+		f, err := r.nextFloat(v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+		return nil
+	case reflect.Slice:
+		if token == STRING && v.Type().Elem().Kind() == reflect.Uint8 {
+			s, err := r.NextString()
+			if err != nil {
+				return err
+			}
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return err
+			}
+			v.SetBytes(b)
+			return nil
+		}
+		if token != BEGIN_ARRAY {
+			return typeError(r, token, v.Type())
+		}
+		return decodeSlice(r, v)
+	case reflect.Array:
+		if token != BEGIN_ARRAY {
+			return typeError(r, token, v.Type())
+		}
+		return decodeArray(r, v)
+	case reflect.Map:
+		if token != BEGIN_OBJECT {
+			return typeError(r, token, v.Type())
+		}
+		return decodeMap(r, v)
+	case reflect.Struct:
+		if token != BEGIN_OBJECT {
+			return typeError(r, token, v.Type())
+		}
+		return decodeStruct(r, v)
+	default:
+		return typeError(r, token, v.Type())
+	}
+}
+
+// Return err unless it means that a value that has been consumed could
+// not be decoded into its Go type, in which case decoding can continue,
+// and the error is kept in *first if it is the first.
+func valueError(err error, first *error) error {
+	switch err.(type) {
+	case nil:
+		return nil
+	case *TypeError, *RangeError, *strconv.NumError, base64.CorruptInputError:
+		if *first == nil {
+			*first = err
+		}
+		return nil
+	default:
+		return err
+	}
+}
+
+// Skip the next value and return a TypeError for it.
+func typeError(r *Reader, token Token, t reflect.Type) error {
+	err := &TypeError{Path: r.Path(), Token: token, Type: t}
+	if err := r.SkipValue(); err != nil {
+		return err
+	}
+	return err
+}
+
+// Read the next value as encoding/json would into an interface{}, except
+// that numbers are kept as Numbers.
+func decodeInterface(r *Reader) (interface{}, error) {
+	token, err := r.Peek()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case BEGIN_OBJECT:
+		if err := r.checkRecursionDepth(); err != nil {
+			return nil, err
+		}
+		m := map[string]interface{}{}
+		if err := r.BeginObject(); err != nil {
+			return nil, err
+		}
+		for {
+			if hasNext, err := r.HasNext(); err != nil {
+				return nil, err
+			} else if !hasNext {
+				break
+			}
+			name, err := r.NextName()
+			if err != nil {
+				return nil, err
+			}
+			if m[name], err = decodeInterface(r); err != nil {
+				return nil, err
+			}
+		}
+		return m, r.EndObject()
+	case BEGIN_ARRAY:
+		if err := r.checkRecursionDepth(); err != nil {
+			return nil, err
+		}
+		a := []interface{}{}
+		if err := r.BeginArray(); err != nil {
+			return nil, err
+		}
+		for {
+			if hasNext, err := r.HasNext(); err != nil {
+				return nil, err
+			} else if !hasNext {
+				break
+			}
+			e, err := decodeInterface(r)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, e)
+		}
+		return a, r.EndArray()
+	case STRING:
+		return r.NextString()
+	case NUMBER:
+		return r.NextNumber()
+	case BOOLEAN:
+		return r.NextBoolean()
+	case NULL:
+		return nil, r.NextNull()
+	default:
+		return nil, IllegalState
+	}
+}
+
+func decodeSlice(r *Reader, v reflect.Value) error {
+	if err := r.BeginArray(); err != nil {
+		return err
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+	var first error
+	n := 0
+	for ; ; n++ {
+		if hasNext, err := r.HasNext(); err != nil {
+			return err
+		} else if !hasNext {
+			break
+		}
+		if n < v.Cap() {
+			v.SetLen(n + 1)
+			// Decode into a zero value, as the element may hold data
+			// from before.
+			v.Index(n).Set(reflect.Zero(v.Type().Elem()))
+		} else {
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+		}
+		if err := valueError(decodeValue(r, v.Index(n)), &first); err != nil {
+			return err
+		}
+	}
+	v.SetLen(n)
+	if err := r.EndArray(); err != nil {
+		return err
+	}
+	return first
+}
+
+func decodeArray(r *Reader, v reflect.Value) error {
+	if err := r.BeginArray(); err != nil {
+		return err
+	}
+	var first error
+	n := 0
+	for ; ; n++ {
+		if hasNext, err := r.HasNext(); err != nil {
+			return err
+		} else if !hasNext {
+			break
+		}
+		if n >= v.Len() {
+			if err := r.SkipValue(); err != nil {
+				return err
+			}
+		} else if err := valueError(decodeValue(r, v.Index(n)), &first); err != nil {
+			return err
+		}
+	}
+	for ; n < v.Len(); n++ {
+		v.Index(n).Set(reflect.Zero(v.Type().Elem()))
+	}
+	if err := r.EndArray(); err != nil {
+		return err
+	}
+	return first
+}
+
+func decodeMap(r *Reader, v reflect.Value) error {
+	t := v.Type()
+	switch t.Key().Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+	default:
+		return typeError(r, BEGIN_OBJECT, t)
+	}
+	if err := r.BeginObject(); err != nil {
+		return err
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+	var first error
+	for {
+		if hasNext, err := r.HasNext(); err != nil {
+			return err
+		} else if !hasNext {
+			break
+		}
+		name, err := r.NextName()
+		if err != nil {
+			return err
+		}
+		key := reflect.New(t.Key()).Elem()
+		switch key.Kind() {
+		case reflect.String:
+			key.SetString(name)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			var i int64
+			if i, err = strconv.ParseInt(name, 10, key.Type().Bits()); err == nil {
+				key.SetInt(i)
+			}
+		default:
+			var u uint64
+			if u, err = strconv.ParseUint(name, 10, key.Type().Bits()); err == nil {
+				key.SetUint(u)
+			}
+		}
+		if err != nil {
+			if err := r.SkipValue(); err != nil {
+				return err
+			}
+			if err := valueError(err, &first); err != nil {
+				return err
+			}
+			continue
+		}
+		elem := reflect.New(t.Elem()).Elem()
+		if err := decodeValue(r, elem); err != nil {
+			if err := valueError(err, &first); err != nil {
+				return err
+			}
+			continue
+		}
+		v.SetMapIndex(key, elem)
+	}
+	if err := r.EndObject(); err != nil {
+		return err
+	}
+	return first
+}
+
+func decodeStruct(r *Reader, v reflect.Value) error {
+	fields := cachedFields(v.Type())
+	if err := r.BeginObject(); err != nil {
+		return err
+	}
+	var first error
+	for {
+		if hasNext, err := r.HasNext(); err != nil {
+			return err
+		} else if !hasNext {
+			break
+		}
+		name, err := r.NextName()
+		if err != nil {
+			return err
+		}
+		f := fields.lookup(name)
+		var fv reflect.Value
+		if f != nil {
+			fv = fieldByIndex(v, f.index)
+		}
+		if !fv.IsValid() {
+			if err := r.SkipValue(); err != nil {
+				return err
+			}
+		} else if f.quoted {
+			if err := valueError(decodeQuoted(r, fv), &first); err != nil {
+				return err
+			}
+		} else if err := valueError(decodeValue(r, fv), &first); err != nil {
+			return err
+		}
+	}
+	if err := r.EndObject(); err != nil {
+		return err
+	}
+	return first
+}
+
+// Return the field of v with the given index sequence, allocating any
+// nil embedded struct pointers along the way, or the zero Value if one
+// cannot be allocated because it is unexported.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// Read the next value, which is a string holding a JSON value, as for a
+// field with the ",string" option, and decode its contents into v.
+func decodeQuoted(r *Reader, v reflect.Value) error {
+	if token, err := r.Peek(); err != nil {
+		return err
+	} else if token == NULL {
+		return decodeValue(r, v)
+	} else if token != STRING {
+		return typeError(r, token, v.Type())
+	}
+	path := r.Path()
+	s, err := r.NextString()
+	if err != nil {
+		return err
+	}
+	quoted := NewBytesReader([]byte(s))
+	if err := decodeValue(quoted, v); err != nil {
+		if e, ok := err.(*TypeError); ok {
+			e.Path = path
+		}
+		return err
+	}
+	if token, err := quoted.Peek(); err != nil {
+		return err
+	} else if token != END_DOCUMENT {
+		return &TypeError{Path: path, Token: STRING, Type: v.Type()}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package rgo
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type decodeEmbedded struct {
+	A int
+	B int `json:"b"`
+}
+
+type DecodeExported struct {
+	C string
+}
+
+type decodeTest struct {
+	decodeEmbedded
+	*DecodeExported
+	Name    string            `json:"name"`
+	Skipped int               `json:"-"`
+	Dash    int               `json:"-,"`
+	Quoted  int64             `json:"quoted,string"`
+	QuotedS string            `json:",string"`
+	Bytes   []byte            `json:"bytes"`
+	Ints    []int             `json:"ints,omitempty"`
+	Array   [2]float64        `json:"array"`
+	Map     map[string]uint8  `json:"map"`
+	IntMap  map[int]bool      `json:"intMap"`
+	Any     interface{}       `json:"any"`
+	Ptr     *decodeEmbedded   `json:"ptr"`
+	Value   Value             `json:"value"`
+	Number  Number            `json:"number"`
+	Nested  []map[string]bool `json:"nested"`
+	B       string            // Distinct from decodeEmbedded.B, which is named b.
+	unused  int
+}
+
+func TestDecode(t *testing.T) {
+	input := `{
+		"A": 1, "b": 2, "C": "c", "name": "n", "Skipped": 3, "-": 4,
+		"quoted": "-5", "QuotedS": "\"q\"", "bytes": "aGVsbG8=",
+		"ints": [6, 7], "array": [8.5, 9, 10], "map": {"x": 11},
+		"intMap": {"12": true}, "any": {"y": [1.50, "z", null, false]},
+		"ptr": {"A": 13}, "value": {"d": 1, "d": 2}, "number": 1e400,
+		"nested": [{"t": true}, {}], "B": "b", "unused": 14, "unknown": [15]
+	}`
+	var v decodeTest
+	v.Ints = []int{1, 2, 3}
+	v.Skipped = -1
+	if err := Decode(NewReader(strings.NewReader(input)), &v); err != nil {
+		t.Errorf("TestDecode:err=%s", err.Error())
+		return
+	}
+	expected := decodeTest{
+		decodeEmbedded: decodeEmbedded{A: 1, B: 2},
+		DecodeExported: &DecodeExported{C: "c"},
+		Name:           "n",
+		Skipped:        -1,
+		Dash:           4,
+		Quoted:         -5,
+		QuotedS:        "q",
+		Bytes:          []byte("hello"),
+		Ints:           []int{6, 7},
+		Array:          [2]float64{8.5, 9},
+		Map:            map[string]uint8{"x": 11},
+		IntMap:         map[int]bool{12: true},
+		Any:            map[string]interface{}{"y": []interface{}{Number("1.50"), "z", nil, false}},
+		Ptr:            &decodeEmbedded{A: 13},
+		Value:          &Object{Members: []Member{{"d", Number("1")}, {"d", Number("2")}}},
+		Number:         "1e400",
+		Nested:         []map[string]bool{{"t": true}, {}},
+		B:              "b",
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("TestDecode:expected=%#v,v=%#v", expected, v)
+	}
+
+	codeInit()
+	var response codeResponse
+	if err := Decode(NewBytesReader(codeJSON), &response); err != nil {
+		t.Errorf("TestDecode:codeResponse:err=%s", err.Error())
+	} else if !reflect.DeepEqual(response, codeStruct) {
+		t.Errorf("TestDecode:codeResponse:different result")
+	}
+}
+
+func TestDecodeStream(t *testing.T) {
+	r := NewReader(strings.NewReader(`{"items": [{"name": "a"}, {"name": "b", "A": "x"}, {"name": "c"}], "n": 3}`))
+	if err := r.BeginObject(); err != nil {
+		t.Errorf("TestDecodeStream:BeginObject:err=%s", err.Error())
+		return
+	}
+	if _, err := r.NextName(); err != nil {
+		t.Errorf("TestDecodeStream:NextName:err=%s", err.Error())
+		return
+	}
+	if err := r.BeginArray(); err != nil {
+		t.Errorf("TestDecodeStream:BeginArray:err=%s", err.Error())
+		return
+	}
+	var names []string
+	for {
+		if hasNext, err := r.HasNext(); err != nil || !hasNext {
+			break
+		}
+		var item decodeTest
+		err := Decode(r, &item)
+		var typeError *TypeError
+		if errors.As(err, &typeError) {
+			if s := err.Error(); s != "rgo: Cannot decode string into int at $.items[1].A" {
+				t.Errorf("TestDecodeStream:err=%s", s)
+			}
+		} else if err != nil {
+			t.Errorf("TestDecodeStream:err=%s", err.Error())
+			return
+		}
+		names = append(names, item.Name)
+	}
+	if err := r.EndArray(); err != nil {
+		t.Errorf("TestDecodeStream:EndArray:err=%s", err.Error())
+	}
+	var n int
+	if _, err := r.NextName(); err != nil {
+		t.Errorf("TestDecodeStream:NextName:err=%s", err.Error())
+	} else if err := Decode(r, &n); err != nil || n != 3 {
+		t.Errorf("TestDecodeStream:n=%d,err=%v", n, err)
+	}
+	if !reflect.DeepEqual(names, []string{"a", "b", "c"}) {
+		t.Errorf("TestDecodeStream:names=%v", names)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		v        interface{}
+		expected string
+	}{
+		{`"a"`, new(int), "rgo: Cannot decode string into int at $"},
+		{`[1, 300]`, new([]uint8), "rgo: 300 out of range for uint8 at $[1]"},
+		{`[1.5]`, new([]int), `strconv.ParseInt: parsing "1.5": invalid syntax`},
+		{`{"a": 1}`, new(map[bool]int), "rgo: Cannot decode object into map[bool]int at $"},
+		{`{"quoted": 5}`, new(decodeTest), "rgo: Cannot decode number into int64 at $.quoted"},
+		{`{"quoted": "5 6"}`, new(decodeTest), "rgo: Cannot decode string into int64 at $.quoted"},
+		{`{"any": [1, }`, new(decodeTest), `rgo: Invalid input at line 1, column 13 (offset 12): found "}", expected value`},
+		{`[1]`, new(error), "rgo: Cannot decode array into error at $"},
+	} {
+		err := Decode(NewReader(strings.NewReader(test.input)), test.v)
+		if err == nil {
+			t.Errorf("TestDecodeErrors:%s:err=nil", test.input)
+		} else if s := err.Error(); s != test.expected {
+			t.Errorf("TestDecodeErrors:%s:expected=%s,err=%s", test.input, test.expected, s)
+		}
+	}
+	var i int
+	if err := Decode(NewReader(strings.NewReader(`1`)), i); err != IllegalArgument {
+		t.Errorf("TestDecodeErrors:err=%v", err)
+	}
+}
+
+func TestDecodeDepth(t *testing.T) {
+	for _, test := range []struct {
+		depth  int
+		v      interface{}
+		offset int64
+	}{
+		{10000, new(interface{}), -1},
+		{10001, new(interface{}), 10000},
+		{1000000, new(interface{}), 10000},
+		{1000000, new([]interface{}), 10000},
+		{1000000, new(Value), 10000},
+	} {
+		input := strings.Repeat("[", test.depth) + strings.Repeat("]", test.depth)
+		err := Decode(NewBytesReader([]byte(input)), test.v)
+		var depthLimitError *DepthLimitError
+		if test.offset < 0 {
+			if err != nil {
+				t.Errorf("TestDecodeDepth:%d:%T:err=%s", test.depth, test.v, err.Error())
+			}
+		} else if !errors.As(err, &depthLimitError) || depthLimitError.Offset != test.offset {
+			t.Errorf("TestDecodeDepth:%d:%T:err=%v", test.depth, test.v, err)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package rgo
+
+import (
+	"bytes"
+	"encoding/base64"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// A CycleError describes a value that refers to itself through a pointer,
+// map or slice, which cannot be encoded.
+type CycleError struct {
+	Type reflect.Type
+}
+
+func (e *CycleError) Error() string {
+	return "rgo: Cannot encode cycle through " + e.Type.String()
+}
+
+// The nesting of pointers, maps and slices after which Encode starts
+// checking for cycles, which ordinary values never reach.
+const startDetectingCyclesAfter = 1000
+
+var (
+	objectType = reflect.TypeOf((*Object)(nil))
+	arrayType  = reflect.TypeOf((*Array)(nil))
+	nullType   = reflect.TypeOf(Null{})
+)
+
+// Write v as a single value, as encoding/json's Marshal would, following
+// the json tags of struct fields.  Structs are written as objects, with
+// the fields of embedded structs promoted, and maps with string or integer
+// keys as objects with their members sorted by name.  A []byte is written
+// as a base64 string, and a nil pointer, interface, map or slice as null.
+// Numbers and Values are written as they are, except that an empty Number
+// is written as 0.  The value is written through BeginObject, Name and the
+// other methods of w, so it may be written anywhere a value is allowed,
+// such as the value of a member of an object that the caller is writing.
+// Returns a TypeError if v, or any value in it, is of a type that cannot
+// be encoded, such as a channel or a function, a CycleError if v refers
+// to itself, and IllegalArgument for a float that is infinite or NaN or a
+// Number that is not valid.  After an error, part of v may have been
+// written, and w should not be used further.
+func Encode(w *Writer, v interface{}) error {
+	e := encoder{w: w}
+	return e.encodeValue(reflect.ValueOf(v))
+}
+
+type encoder struct {
+	w *Writer
+	// The path to the value being encoded, for TypeErrors.
+	path []pathElement
+	// The nesting of pointers, maps and slices being encoded, and once it
+	// exceeds startDetectingCyclesAfter, the ones that are being encoded.
+	ptrLevel int
+	ptrSeen  map[interface{}]struct{}
+}
+
+func (e *encoder) encodeValue(v reflect.Value) error {
+	if !v.IsValid() {
+		return e.w.NullValue()
+	}
+	switch v.Type() {
+	case numberType:
+		if v.Len() == 0 {
+			return e.w.NumberValue("0")
+		}
+		return e.w.NumberValue(Number(v.String()))
+	case objectType, arrayType, nullType:
+		return WriteValue(e.w, v.Interface().(Value))
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return e.w.NullValue()
+		}
+		return e.encodeValue(v.Elem())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return e.w.NullValue()
+		}
+		if err := e.enter(v); err != nil {
+			return err
+		}
+		err := e.encodeValue(v.Elem())
+		e.leave(v)
+		return err
+	case reflect.Bool:
+		return e.w.BoolValue(v.Bool())
+	case reflect.String:
+		return e.w.StringValue(v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return e.w.Int64Value(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return e.w.Uint64Value(v.Uint())
+	case reflect.Float32:
+		return e.w.Float32Value(float32(v.Float()))
+	case reflect.Float64:
+		return e.w.Float64Value(v.Float())
+	case reflect.Slice:
+		if v.IsNil() {
+			return e.w.NullValue()
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return e.w.StringValue(base64.StdEncoding.EncodeToString(v.Bytes()))
+		}
+		if err := e.enter(v); err != nil {
+			return err
+		}
+		err := e.encodeArray(v)
+		e.leave(v)
+		return err
+	case reflect.Array:
+		return e.encodeArray(v)
+	case reflect.Map:
+		if v.IsNil() {
+			return e.w.NullValue()
+		}
+		if err := e.enter(v); err != nil {
+			return err
+		}
+		err := e.encodeMap(v)
+		e.leave(v)
+		return err
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	default:
+		return &TypeError{Path: formatPath(e.path), Token: NO_TOKEN, Type: v.Type()}
+	}
+}
+
+// Start encoding the pointer, map or slice v, returning a CycleError if
+// it is already being encoded.
+func (e *encoder) enter(v reflect.Value) error {
+	if e.ptrLevel++; e.ptrLevel <= startDetectingCyclesAfter {
+		return nil
+	}
+	key := cycleKey(v)
+	if _, ok := e.ptrSeen[key]; ok {
+		return &CycleError{Type: v.Type()}
+	}
+	if e.ptrSeen == nil {
+		e.ptrSeen = map[interface{}]struct{}{}
+	}
+	e.ptrSeen[key] = struct{}{}
+	return nil
+}
+
+// Finish encoding the pointer, map or slice v.
+func (e *encoder) leave(v reflect.Value) {
+	if e.ptrLevel > startDetectingCyclesAfter {
+		delete(e.ptrSeen, cycleKey(v))
+	}
+	e.ptrLevel--
+}
+
+// Return what identifies the pointer, map or slice v, which for a slice
+// includes its length, as slices of different lengths may share an array.
+func cycleKey(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Slice {
+		return struct {
+			ptr uintptr
+			len int
+		}{v.Pointer(), v.Len()}
+	}
+	return v.Pointer()
+}
+
+func (e *encoder) encodeArray(v reflect.Value) error {
+	if err := e.w.BeginArray(); err != nil {
+		return err
+	}
+	e.path = append(e.path, pathElement{})
+	for i := 0; i < v.Len(); i++ {
+		e.path[len(e.path)-1].index = i
+		if err := e.encodeValue(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	e.path = e.path[:len(e.path)-1]
+	return e.w.EndArray()
+}
+
+func (e *encoder) encodeMap(v reflect.Value) error {
+	type member struct {
+		name  string
+		value reflect.Value
+	}
+	members := make([]member, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key := iter.Key()
+		var name string
+		switch key.Kind() {
+		case reflect.String:
+			name = key.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			name = strconv.FormatInt(key.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			name = strconv.FormatUint(key.Uint(), 10)
+		default:
+			return &TypeError{Path: formatPath(e.path), Token: NO_TOKEN, Type: v.Type()}
+		}
+		members = append(members, member{name: name, value: iter.Value()})
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].name < members[j].name })
+	if err := e.w.BeginObject(); err != nil {
+		return err
+	}
+	e.path = append(e.path, pathElement{object: true})
+	for _, m := range members {
+		if err := e.encodeMember(m.name, m.value, false); err != nil {
+			return err
+		}
+	}
+	e.path = e.path[:len(e.path)-1]
+	return e.w.EndObject()
+}
+
+func (e *encoder) encodeStruct(v reflect.Value) error {
+	fields := cachedFields(v.Type())
+	if err := e.w.BeginObject(); err != nil {
+		return err
+	}
+	e.path = append(e.path, pathElement{object: true})
+	for i := range fields.list {
+		f := &fields.list[i]
+		fv, ok := fieldValue(v, f.index)
+		if !ok || (f.omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+		if err := e.encodeMember(f.name, fv, f.quoted); err != nil {
+			return err
+		}
+	}
+	e.path = e.path[:len(e.path)-1]
+	return e.w.EndObject()
+}
+
+func (e *encoder) encodeMember(name string, v reflect.Value, quoted bool) error {
+	e.path[len(e.path)-1].name = name
+	if err := e.w.Name(name); err != nil {
+		return err
+	}
+	if quoted {
+		return e.encodeQuoted(v)
+	}
+	return e.encodeValue(v)
+}
+
+// Write v, which is of a boolean, numeric or string type, as a string
+// holding its JSON encoding, as for a field with the ",string" option.
+func (e *encoder) encodeQuoted(v reflect.Value) error {
+	var buf bytes.Buffer
+	quoted := encoder{w: NewWriter(&buf), path: e.path}
+	if err := quoted.encodeValue(v); err != nil {
+		return err
+	}
+	return e.w.StringValue(buf.String())
+}
+
+// Return the value of the field of v with the given index sequence, and
+// false if it is in an embedded struct through a nil pointer.
+func fieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// Return true if v is false, 0, a nil pointer or interface, or an empty
+// string, array, slice or map, which are omitted by ",omitempty".
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
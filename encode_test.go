@@ -0,0 +1,159 @@
+package rgo
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	v := decodeTest{
+		decodeEmbedded: decodeEmbedded{A: 1, B: 2},
+		Name:           "n",
+		Skipped:        3,
+		Dash:           4,
+		Quoted:         -5,
+		QuotedS:        "q",
+		Bytes:          []byte("hello"),
+		Array:          [2]float64{8.5, 9},
+		Map:            map[string]uint8{"y": 11, "x": 12},
+		IntMap:         map[int]bool{10: true, 9: false},
+		Any:            []interface{}{Number("1.50"), "z", nil, false, map[string]int{}},
+		Value:          &Object{Members: []Member{{"d", Number("1")}, {"d", Null{}}}},
+		Number:         "1e400",
+		Nested:         []map[string]bool{{"t": true}, nil},
+		B:              "b",
+		unused:         14,
+	}
+	expected := `{"A":1,"b":2,"name":"n","-":4,"quoted":"-5","QuotedS":"\"q\"","bytes":"aGVsbG8=","array":[8.5,9],"map":{"x":12,"y":11},"intMap":{"10":true,"9":false},"any":[1.50,"z",null,false,{}],"ptr":null,"value":{"d":1,"d":null},"number":1e400,"nested":[{"t":true},null],"B":"b"}`
+	var buf bytes.Buffer
+	if err := Encode(NewWriter(&buf), &v); err != nil {
+		t.Errorf("TestEncode:err=%s", err.Error())
+	} else if s := buf.String(); s != expected {
+		t.Errorf("TestEncode:expected=%s,s=%s", expected, s)
+	}
+
+	// Round trip through Decode.
+	var decoded decodeTest
+	if err := Decode(NewReader(strings.NewReader(expected)), &decoded); err != nil {
+		t.Errorf("TestEncode:Decode:err=%s", err.Error())
+	} else {
+		buf.Reset()
+		if err := Encode(NewWriter(&buf), decoded); err != nil {
+			t.Errorf("TestEncode:Decode:Encode:err=%s", err.Error())
+		} else if s := buf.String(); s != expected {
+			t.Errorf("TestEncode:Decode:expected=%s,s=%s", expected, s)
+		}
+	}
+
+	// Embedded in a document written by hand.
+	buf.Reset()
+	w := NewWriter(&buf)
+	w.SetIndent("", "  ")
+	if err := w.BeginObject(); err != nil {
+		t.Errorf("TestEncode:BeginObject:err=%s", err.Error())
+	} else if err := w.Name("items"); err != nil {
+		t.Errorf("TestEncode:Name:err=%s", err.Error())
+	} else if err := Encode(w, []*decodeEmbedded{{A: 1}, nil}); err != nil {
+		t.Errorf("TestEncode:Encode:err=%s", err.Error())
+	} else if err := w.Name("n"); err != nil {
+		t.Errorf("TestEncode:Name:err=%s", err.Error())
+	} else if err := Encode(w, 3); err != nil {
+		t.Errorf("TestEncode:Encode:err=%s", err.Error())
+	} else if err := w.EndObject(); err != nil {
+		t.Errorf("TestEncode:EndObject:err=%s", err.Error())
+	} else if s := buf.String(); s != "{\n  \"items\": [\n    {\n      \"A\": 1,\n      \"b\": 0\n    },\n    null\n  ],\n  \"n\": 3\n}" {
+		t.Errorf("TestEncode:s=%s", s)
+	}
+
+	codeInit()
+	buf.Reset()
+	if err := Encode(NewWriter(&buf), &codeStruct); err != nil {
+		t.Errorf("TestEncode:codeStruct:err=%s", err.Error())
+	} else if !bytes.Equal(buf.Bytes(), codeJSON) {
+		t.Errorf("TestEncode:codeStruct:different result")
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	for _, test := range []struct {
+		v        interface{}
+		expected string
+	}{
+		{make(chan int), "rgo: Cannot encode chan int at $"},
+		{[]interface{}{1, map[string]interface{}{"a": func() {}}}, "rgo: Cannot encode func() at $[1].a"},
+		{map[bool]int{true: 1}, "rgo: Cannot encode map[bool]int at $"},
+		{struct{ C complex64 }{}, "rgo: Cannot encode complex64 at $.C"},
+		{struct {
+			F float64 `json:",string"`
+		}{math.Inf(1)}, IllegalArgument.Error()},
+		{Number("x"), IllegalArgument.Error()},
+	} {
+		err := Encode(NewWriter(ioutil.Discard), test.v)
+		if err == nil {
+			t.Errorf("TestEncodeErrors:%#v:err=nil", test.v)
+		} else if s := err.Error(); s != test.expected {
+			t.Errorf("TestEncodeErrors:%#v:expected=%s,err=%s", test.v, test.expected, s)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(NewWriter(&buf), struct{ N *Number }{new(Number)}); err != nil {
+		t.Errorf("TestEncodeErrors:empty Number:err=%s", err.Error())
+	} else if s := buf.String(); s != `{"N":0}` {
+		t.Errorf("TestEncodeErrors:empty Number:s=%s", s)
+	}
+
+	type cycle struct {
+		Next *cycle
+	}
+	c := &cycle{}
+	c.Next = c
+	s := []interface{}{1, nil}
+	s[1] = s
+	m := map[string]interface{}{}
+	m["m"] = m
+	for _, v := range []interface{}{c, s, m} {
+		var cycleError *CycleError
+		if err := Encode(NewWriter(ioutil.Discard), v); !errors.As(err, &cycleError) {
+			t.Errorf("TestEncodeErrors:%T:err=%v", v, err)
+		} else if expected := "rgo: Cannot encode cycle through " + reflect.TypeOf(v).String(); err.Error() != expected {
+			t.Errorf("TestEncodeErrors:%T:expected=%s,err=%s", v, expected, err.Error())
+		}
+	}
+	// Deep values are not cycles.
+	deep := &cycle{}
+	for i := 0; i < 3*startDetectingCyclesAfter; i++ {
+		deep = &cycle{Next: deep}
+	}
+	if err := Encode(NewWriter(ioutil.Discard), deep); err != nil {
+		t.Errorf("TestEncodeErrors:deep:err=%s", err.Error())
+	}
+
+	w := NewWriter(ioutil.Discard)
+	if err := w.BeginObject(); err != nil {
+		t.Errorf("TestEncodeErrors:BeginObject:err=%s", err.Error())
+	} else if err := Encode(w, 1); err != IllegalState {
+		t.Errorf("TestEncodeErrors:err=%v", err)
+	}
+}
+
+func BenchmarkCodeEncodeRgo(b *testing.B) {
+	if codeJSON == nil {
+		b.StopTimer()
+		codeInit()
+		b.StartTimer()
+	}
+	w := NewWriter(ioutil.Discard)
+	for i := 0; i < b.N; i++ {
+		w.Reset(ioutil.Discard)
+		if err := Encode(w, &codeStruct); err != nil {
+			b.Fatal("Encode:", err)
+		}
+	}
+	b.SetBytes(int64(len(codeJSON)))
+}
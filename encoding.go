@@ -0,0 +1,216 @@
+package rgo
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// The character encoding of a JSON text.
+type encoding int
+
+const (
+	utf8Encoding encoding = iota
+	utf16BEEncoding
+	utf16LEEncoding
+	utf32BEEncoding
+	utf32LEEncoding
+)
+
+// Determine the encoding of a JSON text from its first bytes, using the
+// byte order mark if there is one, and otherwise the pattern of nulls in
+// the first four bytes, as described in RFC 4627 section 3.  Return the
+// encoding and the length of the byte order mark, or false if more bytes
+// are needed to decide.
+func detectEncoding(b []byte, eof bool) (encoding, int, bool) {
+	if len(b) >= 4 {
+		if b[0] == 0 && b[1] == 0 && b[2] == 0xfe && b[3] == 0xff {
+			return utf32BEEncoding, 4, true
+		} else if b[0] == 0xff && b[1] == 0xfe && b[2] == 0 && b[3] == 0 {
+			return utf32LEEncoding, 4, true
+		}
+	}
+	if len(b) >= 3 && b[0] == 0xef && b[1] == 0xbb && b[2] == 0xbf {
+		return utf8Encoding, 3, true
+	}
+	if len(b) >= 2 {
+		if b[0] == 0xfe && b[1] == 0xff {
+			return utf16BEEncoding, 2, true
+		} else if b[0] == 0xff && b[1] == 0xfe && (len(b) >= 4 || eof) {
+			return utf16LEEncoding, 2, true
+		} else if b[0] != 0 && b[1] != 0 && b[0] != 0xff && (b[0] != 0xef || b[1] != 0xbb) {
+			return utf8Encoding, 0, true
+		}
+	}
+	if len(b) >= 4 {
+		switch {
+		case b[0] == 0 && b[1] == 0 && b[2] == 0:
+			return utf32BEEncoding, 0, true
+		case b[0] == 0 && b[2] == 0:
+			return utf16BEEncoding, 0, true
+		case b[1] == 0 && b[2] == 0 && b[3] == 0:
+			return utf32LEEncoding, 0, true
+		case b[1] == 0 && b[3] == 0:
+			return utf16LEEncoding, 0, true
+		}
+		return utf8Encoding, 0, true
+	}
+	if !eof {
+		return utf8Encoding, 0, false
+	}
+	if len(b) >= 2 && b[0] == 0 {
+		return utf16BEEncoding, 0, true
+	} else if len(b) >= 2 && b[1] == 0 {
+		return utf16LEEncoding, 0, true
+	}
+	return utf8Encoding, 0, true
+}
+
+// An io.Reader that detects the encoding of a JSON text from its first
+// bytes and transcodes it to UTF-8.  Invalid UTF-16 and UTF-32 input is
+// replaced with U+FFFD.
+type transcoder struct {
+	r        io.Reader
+	encoding encoding
+	detected bool
+	in       []byte
+	out      []byte
+	err      error
+	inBuf    [512]byte
+	outBuf   [1024]byte
+}
+
+func newTranscoder(r io.Reader) *transcoder {
+	return &transcoder{r: r}
+}
+
+// Read from r as if newly created, reusing the buffers.
+func (t *transcoder) reset(r io.Reader) {
+	t.r = r
+	t.encoding = utf8Encoding
+	t.detected = false
+	t.in = nil
+	t.out = nil
+	t.err = nil
+}
+
+// Read until the encoding can be determined.  The bytes that follow the
+// byte order mark are left in t.in.
+func (t *transcoder) detect() error {
+	for {
+		enc, bom, ok := detectEncoding(t.in, t.err != nil)
+		if ok {
+			t.encoding = enc
+			t.detected = true
+			t.in = t.in[:copy(t.in, t.in[bom:])]
+			return nil
+		}
+		if t.err != nil {
+			err := t.err
+			t.err = nil
+			return err
+		}
+		n, err := t.r.Read(t.inBuf[len(t.in):4])
+		t.in = t.inBuf[:len(t.in)+n]
+		t.err = err
+	}
+}
+
+func (t *transcoder) Read(p []byte) (int, error) {
+	if !t.detected {
+		if err := t.detect(); err != nil {
+			return 0, err
+		}
+	}
+	if t.encoding == utf8Encoding {
+		if len(t.in) > 0 {
+			n := copy(p, t.in)
+			t.in = t.in[:copy(t.in, t.in[n:])]
+			return n, nil
+		} else if t.err != nil {
+			err := t.err
+			t.err = nil
+			return 0, err
+		}
+		return t.r.Read(p)
+	}
+	for len(t.out) == 0 {
+		t.decode()
+		if len(t.out) > 0 {
+			break
+		}
+		if t.err != nil {
+			err := t.err
+			t.err = nil
+			return 0, err
+		}
+		n, err := t.r.Read(t.inBuf[len(t.in):])
+		t.in = t.inBuf[:len(t.in)+n]
+		t.err = err
+	}
+	n := copy(p, t.out)
+	t.out = t.out[n:]
+	return n, nil
+}
+
+// Decode the complete code units in t.in into t.out.  If the input has
+// ended, incomplete code units are decoded as U+FFFD.
+func (t *transcoder) decode() {
+	t.out = t.outBuf[:0]
+	eof := t.err != nil
+	i := 0
+	for len(t.out)+utf8.UTFMax <= len(t.outBuf) {
+		var r rune
+		switch t.encoding {
+		case utf16BEEncoding, utf16LEEncoding:
+			if i+2 > len(t.in) {
+				if eof && i < len(t.in) {
+					i = len(t.in)
+					r = utf8.RuneError
+					break
+				}
+				t.in = t.in[:copy(t.in, t.in[i:])]
+				return
+			}
+			r = t.utf16(i)
+			if r >= 0xd800 && r < 0xdc00 {
+				if i+4 > len(t.in) && !eof {
+					t.in = t.in[:copy(t.in, t.in[i:])]
+					return
+				}
+				if i+4 <= len(t.in) {
+					if low := t.utf16(i + 2); low >= 0xdc00 && low < 0xe000 {
+						r = 0x10000 + (r&0x3ff)<<10 + low&0x3ff
+						i += 2
+					}
+				}
+			}
+			i += 2
+		default:
+			if i+4 > len(t.in) {
+				if eof && i < len(t.in) {
+					i = len(t.in)
+					r = utf8.RuneError
+					break
+				}
+				t.in = t.in[:copy(t.in, t.in[i:])]
+				return
+			}
+			if t.encoding == utf32BEEncoding {
+				r = rune(t.in[i])<<24 | rune(t.in[i+1])<<16 | rune(t.in[i+2])<<8 | rune(t.in[i+3])
+			} else {
+				r = rune(t.in[i+3])<<24 | rune(t.in[i+2])<<16 | rune(t.in[i+1])<<8 | rune(t.in[i])
+			}
+			i += 4
+		}
+		n := utf8.EncodeRune(t.outBuf[len(t.out):len(t.out)+utf8.UTFMax], r)
+		t.out = t.outBuf[:len(t.out)+n]
+	}
+	t.in = t.in[:copy(t.in, t.in[i:])]
+}
+
+func (t *transcoder) utf16(i int) rune {
+	if t.encoding == utf16BEEncoding {
+		return rune(t.in[i])<<8 | rune(t.in[i+1])
+	}
+	return rune(t.in[i+1])<<8 | rune(t.in[i])
+}
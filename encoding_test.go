@@ -0,0 +1,107 @@
+package rgo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"testing/iotest"
+	"unicode/utf16"
+)
+
+func encodeText(text string, enc encoding, bom bool) []byte {
+	var buf bytes.Buffer
+	if bom {
+		text = "\ufeff" + text
+	}
+	for _, r := range text {
+		switch enc {
+		case utf8Encoding:
+			buf.WriteRune(r)
+		case utf16BEEncoding, utf16LEEncoding:
+			units := []uint16{uint16(r)}
+			if r >= 0x10000 {
+				r1, r2 := utf16.EncodeRune(r)
+				units = []uint16{uint16(r1), uint16(r2)}
+			}
+			for _, u := range units {
+				if enc == utf16BEEncoding {
+					binary.Write(&buf, binary.BigEndian, u)
+				} else {
+					binary.Write(&buf, binary.LittleEndian, u)
+				}
+			}
+		case utf32BEEncoding:
+			binary.Write(&buf, binary.BigEndian, uint32(r))
+		case utf32LEEncoding:
+			binary.Write(&buf, binary.LittleEndian, uint32(r))
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestEncodings(t *testing.T) {
+	for _, enc := range []encoding{utf8Encoding, utf16BEEncoding, utf16LEEncoding, utf32BEEncoding, utf32LEEncoding} {
+		for _, bom := range []bool{false, true} {
+			data := encodeText("{\"café\":[\"\U0001D11E\",-1]}", enc, bom)
+			r := NewReader(iotest.OneByteReader(bytes.NewBuffer(data)))
+			if err := r.BeginObject(); err != nil {
+				t.Errorf("TestEncodings:%d:%t:BeginObject:err=%s", enc, bom, err.Error())
+				continue
+			}
+			if name, err := r.NextName(); err != nil {
+				t.Errorf("TestEncodings:%d:%t:NextName:err=%s", enc, bom, err.Error())
+				continue
+			} else if name != "café" {
+				t.Errorf("TestEncodings:%d:%t:NextName:name=%s", enc, bom, name)
+				continue
+			}
+			if err := r.BeginArray(); err != nil {
+				t.Errorf("TestEncodings:%d:%t:BeginArray:err=%s", enc, bom, err.Error())
+				continue
+			}
+			if value, err := r.NextString(); err != nil {
+				t.Errorf("TestEncodings:%d:%t:NextString:err=%s", enc, bom, err.Error())
+				continue
+			} else if value != "\U0001D11E" {
+				t.Errorf("TestEncodings:%d:%t:NextString=%s", enc, bom, value)
+				continue
+			}
+			if value, err := r.NextInt(); err != nil {
+				t.Errorf("TestEncodings:%d:%t:NextInt:err=%s", enc, bom, err.Error())
+				continue
+			} else if value != -1 {
+				t.Errorf("TestEncodings:%d:%t:NextInt=%d", enc, bom, value)
+				continue
+			}
+			if err := r.EndArray(); err != nil {
+				t.Errorf("TestEncodings:%d:%t:EndArray:err=%s", enc, bom, err.Error())
+				continue
+			}
+			if err := r.EndObject(); err != nil {
+				t.Errorf("TestEncodings:%d:%t:EndObject:err=%s", enc, bom, err.Error())
+				continue
+			}
+			if token, err := r.Peek(); err != nil {
+				t.Errorf("TestEncodings:%d:%t:Peek:err=%s", enc, bom, err.Error())
+				continue
+			} else if token != END_DOCUMENT {
+				t.Errorf("TestEncodings:%d:%t:Peek:token=%d", enc, bom, token)
+				continue
+			}
+		}
+	}
+	for _, enc := range []encoding{utf8Encoding, utf16BEEncoding, utf16LEEncoding} {
+		r := NewReader(bytes.NewBuffer(encodeText("1", enc, false)))
+		if value, err := r.NextInt(); err != nil {
+			t.Errorf("TestEncodings:%d:NextInt:err=%s", enc, err.Error())
+		} else if value != 1 {
+			t.Errorf("TestEncodings:%d:NextInt=%d", enc, value)
+		}
+	}
+	r := NewReader(bytes.NewBuffer([]byte{'"', 0, 'a', 0, 0x00, 0xd8, '"', 0}))
+	if value, err := r.NextString(); err != nil {
+		t.Errorf("TestEncodings:NextString:err=%s", err.Error())
+	} else if value != "a\ufffd" {
+		t.Errorf("TestEncodings:NextString=%q", value)
+	}
+}
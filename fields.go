@@ -0,0 +1,176 @@
+package rgo
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A struct field that is decoded from and encoded as an object member.
+type field struct {
+	name string
+	// The index sequence for reflect.Value.FieldByIndex, which has more
+	// than one element for the fields of embedded structs.
+	index []int
+	typ   reflect.Type
+	// Set if the name is from a json tag.
+	tagged bool
+	// The ",omitempty" option.
+	omitEmpty bool
+	// The ",string" option, which is only set for fields of boolean,
+	// numeric and string types.
+	quoted bool
+}
+
+// The fields of a struct type.
+type structFields struct {
+	// In the order of the struct, with the fields of embedded structs in
+	// place of the embedded struct.
+	list []field
+	// The indexes in list by name, and by name in lower case.
+	byName  map[string]int
+	byLower map[string]int
+}
+
+// A cache of *structFields by reflect.Type.
+var fieldCache sync.Map
+
+// Return the fields of the struct type t, which are the exported fields
+// and the promoted fields of embedded structs, following the rules of
+// encoding/json.
+func cachedFields(t reflect.Type) *structFields {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.(*structFields)
+	}
+	f, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return f.(*structFields)
+}
+
+func typeFields(t reflect.Type) *structFields {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+	var fields []field
+	visited := map[reflect.Type]bool{}
+	next := []embedded{{typ: t}}
+	for len(next) > 0 {
+		current := next
+		next = nil
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				ft := sf.Type
+				if sf.Anonymous && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if sf.PkgPath != "" && !(sf.Anonymous && ft.Kind() == reflect.Struct) {
+					// Unexported, and not an embedded struct whose
+					// exported fields are promoted.
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, options := tag, ""
+				if i := strings.IndexByte(tag, ','); i >= 0 {
+					name, options = tag[:i], tag[i:]
+				}
+				index := append(append([]int(nil), e.index...), i)
+				if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+					next = append(next, embedded{typ: ft, index: index})
+					continue
+				}
+				if sf.PkgPath != "" {
+					continue
+				}
+				f := field{
+					name:      name,
+					index:     index,
+					typ:       sf.Type,
+					tagged:    name != "",
+					omitEmpty: strings.Contains(options+",", ",omitempty,"),
+				}
+				if f.name == "" {
+					f.name = sf.Name
+				}
+				if strings.Contains(options+",", ",string,") {
+					switch sf.Type.Kind() {
+					case reflect.Bool, reflect.String,
+						reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+						reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+						reflect.Float32, reflect.Float64:
+						f.quoted = true
+					}
+				}
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	// Of the fields with the same name, keep the one that is least
+	// deeply nested, preferring a tagged field, and drop them all if that
+	// is ambiguous.
+	byName := map[string][]int{}
+	for i, f := range fields {
+		byName[f.name] = append(byName[f.name], i)
+	}
+	keep := make([]bool, len(fields))
+	for _, indexes := range byName {
+		best, ambiguous := -1, false
+		for _, i := range indexes {
+			switch {
+			case best < 0 || len(fields[i].index) < len(fields[best].index):
+				best, ambiguous = i, false
+			case len(fields[i].index) > len(fields[best].index):
+			case fields[i].tagged && !fields[best].tagged:
+				best, ambiguous = i, false
+			case fields[i].tagged == fields[best].tagged:
+				ambiguous = true
+			}
+		}
+		if !ambiguous {
+			keep[best] = true
+		}
+	}
+	sf := &structFields{byName: map[string]int{}, byLower: map[string]int{}}
+	for i, f := range fields {
+		if keep[i] {
+			sf.list = append(sf.list, f)
+		}
+	}
+	sort.SliceStable(sf.list, func(i, j int) bool {
+		a, b := sf.list[i].index, sf.list[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	for i, f := range sf.list {
+		sf.byName[f.name] = i
+		if _, ok := sf.byLower[strings.ToLower(f.name)]; !ok {
+			sf.byLower[strings.ToLower(f.name)] = i
+		}
+	}
+	return sf
+}
+
+// Return the field with the given name, matching case-insensitively if
+// there is no exact match, or nil.
+func (sf *structFields) lookup(name string) *field {
+	if i, ok := sf.byName[name]; ok {
+		return &sf.list[i]
+	}
+	if i, ok := sf.byLower[strings.ToLower(name)]; ok {
+		return &sf.list[i]
+	}
+	return nil
+}
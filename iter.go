@@ -0,0 +1,111 @@
+//go:build go1.23
+
+package rgo
+
+import (
+	"iter"
+)
+
+// Return a sequence of the indexes of the elements of the next value,
+// which must be an array, for use with range.  BeginArray and EndArray
+// are called automatically.  The loop body may read each element with
+// any method, and if it does not, the element is skipped with SkipValue.
+// If the loop ends early, the rest of the array is skipped.  If an error
+// occurs, it is yielded with an index of -1, the sequence ends, and the
+// error is also returned by Err.
+func (r *Reader) Elements() iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		r.err = nil
+		if err := r.BeginArray(); err != nil {
+			r.err = err
+			yield(-1, err)
+			return
+		}
+		more, err := r.elements(func(_ string, index int) bool {
+			return yield(index, nil)
+		})
+		if err == nil {
+			err = r.EndArray()
+		}
+		// Set even if nil, as a nested sequence may have failed.
+		r.err = err
+		if err != nil && more {
+			yield(-1, err)
+		}
+	}
+}
+
+// Return a sequence of the names of the members of the next value, which
+// must be an object, for use with range.  BeginObject and EndObject are
+// called automatically, as is NextName.  The loop body may read the value
+// of each member with any method, and if it does not, the value is
+// skipped with SkipValue.  If the loop ends early, the rest of the object
+// is skipped.  If an error occurs, it is yielded with an empty name, the
+// sequence ends, and the error is also returned by Err.
+func (r *Reader) Members() iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		r.err = nil
+		if err := r.BeginObject(); err != nil {
+			r.err = err
+			yield("", err)
+			return
+		}
+		more, err := r.elements(func(name string, _ int) bool {
+			return yield(name, nil)
+		})
+		if err == nil {
+			err = r.EndObject()
+		}
+		// Set even if nil, as a nested sequence may have failed.
+		r.err = err
+		if err != nil && more {
+			yield("", err)
+		}
+	}
+}
+
+// Call yield with the name or index of each element of the array or
+// object that has just been begun, skipping whatever it does not read,
+// until it returns false, after which the rest of the elements are
+// skipped.  Return false if yield returned false.
+func (r *Reader) elements(yield func(name string, index int) bool) (bool, error) {
+	depth := len(r.path)
+	more := true
+	for {
+		hasNext, err := r.HasNext()
+		if err != nil {
+			return more, err
+		} else if !hasNext {
+			return more, nil
+		}
+		var name string
+		if r.path[depth-1].object {
+			if name, err = r.NextName(); err != nil {
+				return more, err
+			}
+		}
+		index := r.path[depth-1].index
+		if more {
+			more = yield(name, index)
+			if len(r.path) != depth {
+				// A container was begun and not ended, which is
+				// expected if a nested sequence failed.
+				if r.err != nil {
+					return more, r.err
+				}
+				return more, IllegalState
+			}
+		}
+		if r.path[depth-1].index == index {
+			if err := r.SkipValue(); err != nil {
+				return more, err
+			}
+		}
+	}
+}
+
+// Return the error that ended the last sequence returned by Elements or
+// Members, or nil if it ended normally.
+func (r *Reader) Err() error {
+	return r.err
+}
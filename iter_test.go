@@ -0,0 +1,176 @@
+//go:build go1.23
+
+package rgo
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func decodeResponseIter(response *codeResponse, r *Reader) error {
+	for name, err := range r.Members() {
+		if err != nil {
+			return err
+		}
+		switch name {
+		case "tree":
+			response.Tree = &codeNode{}
+			if err := decodeNodeIter(response.Tree, r); err != nil {
+				return err
+			}
+		case "username":
+			if response.Username, err = r.NextString(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func decodeNodeIter(node *codeNode, r *Reader) error {
+	for name, err := range r.Members() {
+		if err != nil {
+			return err
+		}
+		switch name {
+		case "name":
+			node.Name, err = r.NextString()
+		case "kids":
+			for _, err := range r.Elements() {
+				if err != nil {
+					return err
+				}
+				kid := &codeNode{}
+				if err := decodeNodeIter(kid, r); err != nil {
+					return err
+				}
+				node.Kids = append(node.Kids, kid)
+			}
+		case "cl_weight":
+			node.CLWeight, err = r.NextFloat64()
+		case "touches":
+			node.Touches, err = r.NextInt()
+		case "min_t":
+			node.MinT, err = r.NextInt64()
+		case "max_t":
+			node.MaxT, err = r.NextInt64()
+		case "mean_t":
+			node.MeanT, err = r.NextInt64()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func BenchmarkCodeDecoderIterRgo(b *testing.B) {
+	if codeJSON == nil {
+		b.StopTimer()
+		codeInit()
+		b.StartTimer()
+	}
+	var buf bytes.Buffer
+	r := NewReader(&buf)
+	var response codeResponse
+	for i := 0; i < b.N; i++ {
+		buf.Write(codeJSON)
+		if err := decodeResponseIter(&response, r); err != nil {
+			b.Fatal("decodeResponseIter:", err)
+		}
+	}
+	b.SetBytes(int64(len(codeJSON)))
+}
+
+func TestElementsMembers(t *testing.T) {
+	codeInit()
+	var response codeResponse
+	var buf bytes.Buffer
+	if err := decodeResponseIter(&response, NewBytesReader(codeJSON)); err != nil {
+		t.Errorf("TestElementsMembers:decodeResponseIter:err=%s", err.Error())
+	} else if err := encodeResponse(&response, NewWriter(&buf)); err != nil {
+		t.Errorf("TestElementsMembers:encodeResponse:err=%s", err.Error())
+	} else if !bytes.Equal(buf.Bytes(), codeJSON) {
+		t.Errorf("TestElementsMembers:decodeResponseIter:different result")
+	}
+
+	for _, test := range []struct {
+		input    string
+		expected string
+	}{
+		{`{"a": 1, "b": [2, {"c": 3}, 4], "d": {"e": 5}} 6`, `a=1 b[0]=2 b[1][2]=4 b d end 9"6" `},
+		{`{"b": [2, 3, 4, 5], "a": 1} 6`, `b[0]=2 b[1]=3 break a=1 end 9"6" `},
+		{`{"a": [1, 2}`, `a err=rgo: Invalid input at line 1, column 12 (offset 11): found "}", expected ',' or ']' Err=true`},
+		{`[1]`, `err=rgo: Illegal state Err=true`},
+		{`{"b": [2, x]}`, `b[0]=2 b err=rgo: Invalid input at line 1, column 11 (offset 10): found "x", expected value Err=true err=rgo: Invalid input at line 1, column 11 (offset 10): found "x", expected value Err=true`},
+	} {
+		r := NewReader(strings.NewReader(test.input))
+		var buf bytes.Buffer
+		for name, err := range r.Members() {
+			if err != nil {
+				fmt.Fprintf(&buf, "err=%s Err=%t", err.Error(), r.Err() == err)
+				break
+			}
+			buf.WriteString(name)
+			switch name {
+			case "a":
+				// Read the value of "a" if it is a number.
+				if value, err := r.NextInt(); err == nil {
+					fmt.Fprintf(&buf, "=%d", value)
+				}
+			case "b":
+				for i, err := range r.Elements() {
+					if err != nil {
+						fmt.Fprintf(&buf, " err=%s Err=%t", err.Error(), r.Err() == err)
+						break
+					}
+					// Leave the object at b[1] unread.
+					fmt.Fprintf(&buf, "[%d]", i)
+					if i == 1 && strings.HasPrefix(test.input, `{"a"`) {
+						continue
+					} else if value, err := r.NextInt(); err == nil {
+						fmt.Fprintf(&buf, "=%d", value)
+					}
+					if i == 1 {
+						buf.WriteString(" break")
+						break
+					}
+					buf.WriteString(" b")
+				}
+			}
+			buf.WriteString(" ")
+		}
+		if r.Err() == nil {
+			buf.WriteString("end ")
+			buf.WriteString(readTokens(r))
+		}
+		if s := buf.String(); s != test.expected {
+			t.Errorf("TestElementsMembers:%s:expected=%s,s=%s", test.input, test.expected, s)
+		}
+	}
+
+	// A nested sequence fails, but the outer sequence ends normally.
+	r := NewReader(strings.NewReader(`[[1],4]`))
+	buf.Reset()
+	for i, err := range r.Elements() {
+		if err != nil {
+			t.Errorf("TestElementsMembers:[[1],4]:err=%s", err.Error())
+			break
+		}
+		for j, err := range r.Elements() {
+			if err != nil {
+				fmt.Fprintf(&buf, "%d err=%s Err=%t ", i, err.Error(), r.Err() == err)
+				break
+			}
+			fmt.Fprintf(&buf, "%d[%d] ", i, j)
+		}
+	}
+	if s := buf.String(); s != "0[0] 1 err=rgo: Illegal state Err=true " {
+		t.Errorf("TestElementsMembers:[[1],4]:s=%s", s)
+	}
+	if err := r.Err(); err != nil {
+		t.Errorf("TestElementsMembers:[[1],4]:Err=%s", err.Error())
+	}
+}
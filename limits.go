@@ -0,0 +1,109 @@
+package rgo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Limits on the input accepted by a Reader, which bound the memory and
+// time that hostile input can use.  A limit of zero means no limit.
+type Limits struct {
+	// The maximum nesting depth of arrays and objects.  If it is zero,
+	// ReadValue and Decode, which recurse for each level of nesting,
+	// still stop at a depth of 10000, as encoding/json does.
+	MaxDepth int
+	// The maximum length in bytes of a string or name as it appears in
+	// the input, not counting its quotes.
+	MaxStringBytes int
+	// The maximum length in bytes of a number.
+	MaxNumberBytes int
+	// The maximum number of bytes read.  Input that is transcoded from
+	// UTF-16 or UTF-32 is counted in bytes of UTF-8.
+	MaxTotalBytes int64
+	// The maximum number of tokens read, not counting END_DOCUMENT.
+	MaxTokens int64
+}
+
+// Matched with errors.Is by each of the errors returned when the input
+// exceeds one of the Limits of a Reader.
+var LimitExceeded = errors.New("rgo: Limit exceeded")
+
+// A DepthLimitError describes an array or object that is nested more
+// deeply than Limits.MaxDepth.
+type DepthLimitError struct {
+	// The offset of the array or object.
+	Offset int64
+	Limit  int
+}
+
+func (e *DepthLimitError) Error() string {
+	return fmt.Sprintf("rgo: Nesting depth exceeds limit of %d at offset %d", e.Limit, e.Offset)
+}
+
+func (e *DepthLimitError) Unwrap() error {
+	return LimitExceeded
+}
+
+// A StringLimitError describes a string or name that is longer than
+// Limits.MaxStringBytes.
+type StringLimitError struct {
+	// The offset of the string or name.
+	Offset int64
+	Limit  int
+}
+
+func (e *StringLimitError) Error() string {
+	return fmt.Sprintf("rgo: String length exceeds limit of %d bytes at offset %d", e.Limit, e.Offset)
+}
+
+func (e *StringLimitError) Unwrap() error {
+	return LimitExceeded
+}
+
+// A NumberLimitError describes a number that is longer than
+// Limits.MaxNumberBytes.
+type NumberLimitError struct {
+	// The offset of the number.
+	Offset int64
+	Limit  int
+}
+
+func (e *NumberLimitError) Error() string {
+	return fmt.Sprintf("rgo: Number length exceeds limit of %d bytes at offset %d", e.Limit, e.Offset)
+}
+
+func (e *NumberLimitError) Unwrap() error {
+	return LimitExceeded
+}
+
+// A SizeLimitError describes input that is longer than
+// Limits.MaxTotalBytes.
+type SizeLimitError struct {
+	// The offset of the first byte past the limit.
+	Offset int64
+	Limit  int64
+}
+
+func (e *SizeLimitError) Error() string {
+	return fmt.Sprintf("rgo: Input exceeds limit of %d bytes at offset %d", e.Limit, e.Offset)
+}
+
+func (e *SizeLimitError) Unwrap() error {
+	return LimitExceeded
+}
+
+// A TokenLimitError describes input with more tokens than
+// Limits.MaxTokens.
+type TokenLimitError struct {
+	// The offset from which the token past the limit was read.
+	Offset int64
+	Limit  int64
+}
+
+func (e *TokenLimitError) Error() string {
+	return fmt.Sprintf("rgo: Number of tokens exceeds limit of %d at offset %d", e.Limit, e.Offset)
+}
+
+func (e *TokenLimitError) Unwrap() error {
+	return LimitExceeded
+}
@@ -0,0 +1,118 @@
+package rgo
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLimits(t *testing.T) {
+	for _, test := range []struct {
+		input  string
+		limits Limits
+		// The offset of the error, or -1 if the input is within the limits.
+		offset int64
+		err    error
+	}{
+		{`[[[]]]`, Limits{MaxDepth: 3}, -1, nil},
+		{`[[[[]]]]`, Limits{MaxDepth: 3}, 3, &DepthLimitError{}},
+		{`[{"a":{}}]`, Limits{MaxDepth: 2}, 6, &DepthLimitError{}},
+		{`["abc","\u0041"]`, Limits{MaxStringBytes: 6}, -1, nil},
+		{`["abc","\u00411"]`, Limits{MaxStringBytes: 6}, 7, &StringLimitError{}},
+		{`{"abcd":1}`, Limits{MaxStringBytes: 3}, 1, &StringLimitError{}},
+		{`[-1.5e10,1]`, Limits{MaxNumberBytes: 7}, -1, nil},
+		{`[1,-1.5e100]`, Limits{MaxNumberBytes: 7}, 3, &NumberLimitError{}},
+		{`[1, 2]`, Limits{MaxTotalBytes: 6}, -1, nil},
+		{`[1, 2] `, Limits{MaxTotalBytes: 6}, 6, &SizeLimitError{}},
+		{`["abcdef"]`, Limits{MaxTotalBytes: 6}, 6, &SizeLimitError{}},
+		{`[1,{"a":2}]`, Limits{MaxTokens: 7}, -1, nil},
+		{`[1,{"a":2}] 3`, Limits{MaxTokens: 7}, 11, &TokenLimitError{}},
+	} {
+		for _, newReader := range []func() *Reader{
+			func() *Reader { return NewReader(bytes.NewBufferString(test.input)) },
+			func() *Reader { return NewBytesReader([]byte(test.input)) },
+			func() *Reader { return NewReaderSize(iotest.OneByteReader(bytes.NewBufferString(test.input)), 1) },
+		} {
+			for _, skip := range []bool{false, true} {
+				r := newReader()
+				r.SetLimits(test.limits)
+				var err error
+				if skip {
+					for i := 0; err == nil && i < 3; i++ {
+						err = r.SkipValue()
+					}
+				} else {
+					err = readAll(r)
+				}
+				if test.offset < 0 {
+					if err != nil {
+						t.Errorf("TestLimits:%s:skip=%t:err=%s", test.input, skip, err.Error())
+					}
+					continue
+				}
+				if !errors.Is(err, LimitExceeded) {
+					if err == nil {
+						t.Errorf("TestLimits:%s:skip=%t:err=nil", test.input, skip)
+					} else {
+						t.Errorf("TestLimits:%s:skip=%t:err=%s", test.input, skip, err.Error())
+					}
+					continue
+				}
+				var offset, limit int64
+				switch e := err.(type) {
+				case *DepthLimitError:
+					offset, limit = e.Offset, int64(test.limits.MaxDepth)
+				case *StringLimitError:
+					offset, limit = e.Offset, int64(test.limits.MaxStringBytes)
+				case *NumberLimitError:
+					offset, limit = e.Offset, int64(test.limits.MaxNumberBytes)
+				case *SizeLimitError:
+					offset, limit = e.Offset, test.limits.MaxTotalBytes
+				case *TokenLimitError:
+					offset, limit = e.Offset, test.limits.MaxTokens
+				}
+				if reflect.TypeOf(err) != reflect.TypeOf(test.err) || limit == 0 {
+					t.Errorf("TestLimits:%s:skip=%t:err=%s", test.input, skip, err.Error())
+				}
+				if offset != test.offset {
+					t.Errorf("TestLimits:%s:skip=%t:err=%s", test.input, skip, err.Error())
+				}
+			}
+		}
+	}
+}
+
+// Read every token from r, returning the first error.
+func readAll(r *Reader) error {
+	for {
+		token, err := r.Peek()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case BEGIN_ARRAY:
+			err = r.BeginArray()
+		case END_ARRAY:
+			err = r.EndArray()
+		case BEGIN_OBJECT:
+			err = r.BeginObject()
+		case END_OBJECT:
+			err = r.EndObject()
+		case NAME:
+			_, err = r.NextName()
+		case STRING, NUMBER:
+			_, err = r.NextString()
+		case BOOLEAN:
+			_, err = r.NextBoolean()
+		case NULL:
+			err = r.NextNull()
+		case END_DOCUMENT:
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
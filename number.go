@@ -0,0 +1,108 @@
+package rgo
+
+import (
+	"math/big"
+	"strconv"
+)
+
+// A JSON number, holding its literal text exactly as it appeared in the
+// input, so that no precision is lost.
+type Number string
+
+func (n Number) String() string {
+	return string(n)
+}
+
+// Return the number as an int64.
+func (n Number) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+// Return the number as a uint64.
+func (n Number) Uint64() (uint64, error) {
+	return strconv.ParseUint(string(n), 10, 64)
+}
+
+// Return the number as a float64, rounding if necessary.
+func (n Number) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// Return the number as a big.Int.  Numbers with fractions or exponents
+// are accepted if their value is an integer.
+func (n Number) BigInt() (*big.Int, error) {
+	if !validNumber([]byte(n)) {
+		return nil, n.syntaxError("BigInt")
+	}
+	if i, ok := new(big.Int).SetString(string(n), 10); ok {
+		return i, nil
+	}
+	if r, ok := new(big.Rat).SetString(string(n)); ok && r.IsInt() {
+		return r.Num(), nil
+	}
+	return nil, n.syntaxError("BigInt")
+}
+
+// Return the number as a big.Float, with enough precision for all of its
+// decimal digits.
+func (n Number) BigFloat() (*big.Float, error) {
+	if !validNumber([]byte(n)) {
+		return nil, n.syntaxError("BigFloat")
+	}
+	prec := uint(4 * len(n))
+	if prec < 64 {
+		prec = 64
+	}
+	f, _, err := big.ParseFloat(string(n), 10, prec, big.ToNearestEven)
+	if err != nil {
+		return nil, n.syntaxError("BigFloat")
+	}
+	return f, nil
+}
+
+// Return the exact value of the number as a big.Rat.
+func (n Number) Rat() (*big.Rat, error) {
+	if !validNumber([]byte(n)) {
+		return nil, n.syntaxError("Rat")
+	}
+	if r, ok := new(big.Rat).SetString(string(n)); ok {
+		return r, nil
+	}
+	return nil, n.syntaxError("Rat")
+}
+
+func (n Number) syntaxError(fn string) error {
+	return &strconv.NumError{Func: fn, Num: string(n), Err: strconv.ErrSyntax}
+}
+
+// Return the exact decimal representation of r, or false if it has none.
+func ratString(r *big.Rat) (string, bool) {
+	if r.IsInt() {
+		return r.Num().String(), true
+	}
+	denom := new(big.Int).Set(r.Denom())
+	twos, fives := 0, 0
+	two, five := big.NewInt(2), big.NewInt(5)
+	var q, m big.Int
+	for {
+		if q.DivMod(denom, two, &m); m.Sign() != 0 {
+			break
+		}
+		denom.Set(&q)
+		twos++
+	}
+	for {
+		if q.DivMod(denom, five, &m); m.Sign() != 0 {
+			break
+		}
+		denom.Set(&q)
+		fives++
+	}
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return "", false
+	}
+	if twos < fives {
+		twos = fives
+	}
+	return r.FloatString(twos), true
+}
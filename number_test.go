@@ -0,0 +1,124 @@
+package rgo
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+func TestNumber(t *testing.T) {
+	r := NewReader(bytes.NewBufferString(`[123456789012345678901234567890.5, 18446744073709551615, 1.5e3, "12", "x", 0.1]`))
+	if err := r.BeginArray(); err != nil {
+		t.Errorf("TestNumber:BeginArray:err=%s", err.Error())
+		return
+	}
+	n, err := r.NextNumber()
+	if err != nil {
+		t.Errorf("TestNumber:NextNumber:err=%s", err.Error())
+		return
+	} else if n != "123456789012345678901234567890.5" {
+		t.Errorf("TestNumber:NextNumber=%s", n)
+		return
+	}
+	if rat, err := n.Rat(); err != nil {
+		t.Errorf("TestNumber:Rat:err=%s", err.Error())
+	} else if s, _ := ratString(rat); s != string(n) {
+		t.Errorf("TestNumber:Rat=%s", rat)
+	}
+	if _, err := n.BigInt(); err == nil {
+		t.Errorf("TestNumber:BigInt:err=nil")
+	}
+	if f, err := n.BigFloat(); err != nil {
+		t.Errorf("TestNumber:BigFloat:err=%s", err.Error())
+	} else if s := f.Text('f', 1); s != string(n) {
+		t.Errorf("TestNumber:BigFloat=%s", s)
+	}
+	if n, err = r.NextNumber(); err != nil {
+		t.Errorf("TestNumber:NextNumber:err=%s", err.Error())
+		return
+	}
+	if value, err := n.Uint64(); err != nil || value != 18446744073709551615 {
+		t.Errorf("TestNumber:Uint64=%d,err=%v", value, err)
+	}
+	if _, err := n.Int64(); err == nil {
+		t.Errorf("TestNumber:Int64:err=nil")
+	}
+	if n, err = r.NextNumber(); err != nil {
+		t.Errorf("TestNumber:NextNumber:err=%s", err.Error())
+		return
+	}
+	if i, err := n.BigInt(); err != nil || i.Int64() != 1500 {
+		t.Errorf("TestNumber:BigInt=%s,err=%v", i, err)
+	}
+	if value, err := n.Float64(); err != nil || value != 1500 {
+		t.Errorf("TestNumber:Float64=%g,err=%v", value, err)
+	}
+	if n, err = r.NextNumber(); err != nil || n != "12" {
+		t.Errorf("TestNumber:NextNumber=%s,err=%v", n, err)
+		return
+	}
+	if _, err = r.NextNumber(); err == nil {
+		t.Errorf("TestNumber:NextNumber:err=nil")
+		return
+	}
+	if n, err = r.NextNumber(); err != nil || n != "0.1" {
+		t.Errorf("TestNumber:NextNumber=%s,err=%v", n, err)
+		return
+	}
+	if err := r.EndArray(); err != nil {
+		t.Errorf("TestNumber:EndArray:err=%s", err.Error())
+		return
+	}
+}
+
+func TestWriteNumber(t *testing.T) {
+	buf := bytes.Buffer{}
+	w := NewWriter(&buf)
+	if err := w.BeginArray(); err != nil {
+		t.Errorf("TestWriteNumber:BeginArray:err=%s", err.Error())
+		return
+	}
+	for _, value := range []interface{}{
+		Number("123456789012345678901234567890.5"),
+		Number("-0.0e-0"),
+		new(big.Int).Lsh(big.NewInt(1), 100),
+		big.NewFloat(1.5e300),
+		big.NewRat(-3, 8),
+		big.NewRat(7, 1),
+	} {
+		if err := w.Value(value); err != nil {
+			t.Errorf("TestWriteNumber:Value:%v:err=%s", value, err.Error())
+			return
+		}
+	}
+	for _, value := range []interface{}{
+		Number("01"),
+		Number("1."),
+		Number("2.e3"),
+		Number("-.5"),
+		Number("-e5"),
+		Number("-0.E1"),
+		Number("+1"),
+		Number(""),
+		Number("NaN"),
+		big.NewRat(1, 3),
+		new(big.Float).SetInf(false),
+	} {
+		if err := w.Value(value); err != IllegalArgument {
+			if err == nil {
+				t.Errorf("TestWriteNumber:Value:%v:err=nil", value)
+			} else {
+				t.Errorf("TestWriteNumber:Value:%v:err=%s", value, err.Error())
+			}
+			return
+		}
+	}
+	if err := w.EndArray(); err != nil {
+		t.Errorf("TestWriteNumber:EndArray:err=%s", err.Error())
+		return
+	}
+	if s := buf.String(); s != "[123456789012345678901234567890.5,-0.0e-0,1267650600228229401496703205376,1.5e+300,-0.375,7]" {
+		t.Errorf("TestWriteNumber:s=%s", s)
+		return
+	}
+}
@@ -0,0 +1,60 @@
+package rgo
+
+import (
+	"io"
+	"sync"
+)
+
+var readerPool = sync.Pool{New: func() interface{} { return new(Reader) }}
+
+var writerPool = sync.Pool{New: func() interface{} { return new(Writer) }}
+
+// The largest window of a Reader that ReleaseReader returns to the pool.
+// A Reader whose window has grown to hold a large token is dropped
+// instead, so that the pool does not keep large buffers that most uses
+// do not need.
+const maxPooledWindowSize = 64 << 10
+
+// Return a Reader from a pool, reset to read from r as if created by
+// NewReader(r).  When it is no longer needed, it should be returned to the
+// pool with ReleaseReader.
+func AcquireReader(r io.Reader) *Reader {
+	reader := readerPool.Get().(*Reader)
+	reader.Reset(r)
+	return reader
+}
+
+// Return a Reader from a pool, reset to read from b as if created by
+// NewBytesReader(b).  When it is no longer needed, it should be returned
+// to the pool with ReleaseReader.
+func AcquireBytesReader(b []byte) *Reader {
+	reader := readerPool.Get().(*Reader)
+	reader.ResetBytes(b)
+	return reader
+}
+
+// Return r to the pool used by AcquireReader and AcquireBytesReader, unless
+// its buffer has grown larger than 64 KiB.  r must not be used afterwards.
+func ReleaseReader(r *Reader) {
+	r.ResetBytes(nil)
+	if cap(r.window) > maxPooledWindowSize {
+		return
+	}
+	readerPool.Put(r)
+}
+
+// Return a Writer from a pool, reset to write to w as if created by
+// NewWriter(w).  When it is no longer needed, it should be returned to the
+// pool with ReleaseWriter.
+func AcquireWriter(w io.Writer) *Writer {
+	writer := writerPool.Get().(*Writer)
+	writer.Reset(w)
+	return writer
+}
+
+// Return w to the pool used by AcquireWriter.  w must not be used
+// afterwards.
+func ReleaseWriter(w *Writer) {
+	w.Reset(nil)
+	writerPool.Put(w)
+}
@@ -0,0 +1,121 @@
+package rgo
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReset(t *testing.T) {
+	r := NewReader(bytes.NewBufferString(`// comment` + "\n" + `{"a":[1,`))
+	r.SetLenient(true)
+	r.SetLimits(Limits{MaxDepth: 1})
+	r.SetDocumentMode(JSONLines)
+	if err := r.BeginObject(); err != nil {
+		t.Errorf("TestReset:BeginObject:err=%s", err.Error())
+		return
+	}
+	if _, err := r.PeekN(3); !errors.Is(err, LimitExceeded) {
+		t.Errorf("TestReset:PeekN:err=%v", err)
+	}
+	for _, reset := range []func(string){
+		func(input string) { r.Reset(bytes.NewBufferString(input)) },
+		func(input string) { r.ResetBytes([]byte(input)) },
+		func(input string) { r.Reset(bytes.NewBufferString(input)) },
+	} {
+		reset("[[1],\n\"\xff\"]")
+		if r.IsLenient() {
+			t.Errorf("TestReset:IsLenient")
+		}
+		if s := readTokens(r); s != "[ [ 9\"1\" ] err=rgo: Invalid input at line 2, column 2 (offset 7): found \"\\xff\", expected UTF-8 path=$[1]" {
+			t.Errorf("TestReset:s=%s", s)
+		}
+		reset("\xef\xbb\xbf[true]")
+		if s := readTokens(r); s != "[ true ] " {
+			t.Errorf("TestReset:s=%s", s)
+		}
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetIndent("", "  ")
+	w.SetDocumentMode(JSONSequence)
+	if err := w.BeginArray(); err != nil {
+		t.Errorf("TestReset:BeginArray:err=%s", err.Error())
+		return
+	}
+	buf.Reset()
+	w.Reset(&buf)
+	for _, err := range []error{w.BeginArray(), w.IntValue(1), w.EndArray()} {
+		if err != nil {
+			t.Errorf("TestReset:err=%s", err.Error())
+		}
+	}
+	// The DocumentMode is reset, so a second top-level value is rejected.
+	if err := w.IntValue(2); err != IllegalState {
+		t.Errorf("TestReset:IntValue:err=%v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("TestReset:Close:err=%s", err.Error())
+	}
+	if s := buf.String(); s != "[1]" {
+		t.Errorf("TestReset:s=%q", s)
+	}
+}
+
+func TestPool(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		r := AcquireBytesReader([]byte(`{"a":"b"}`))
+		if s := readTokens(r); s != `{ "a": 10"b" } ` {
+			t.Errorf("TestPool:s=%s", s)
+		}
+		ReleaseReader(r)
+		r = AcquireReader(bytes.NewBufferString(`[1]`))
+		if s := readTokens(r); s != `[ 9"1" ] ` {
+			t.Errorf("TestPool:s=%s", s)
+		}
+		ReleaseReader(r)
+
+		var buf bytes.Buffer
+		w := AcquireWriter(&buf)
+		if err := w.StringValue("x"); err != nil {
+			t.Errorf("TestPool:StringValue:err=%s", err.Error())
+		}
+		ReleaseWriter(w)
+		if s := buf.String(); s != `"x"` {
+			t.Errorf("TestPool:s=%s", s)
+		}
+	}
+
+	// A Reader whose window has grown past the limit is not pooled.
+	large := `"` + strings.Repeat("x", 2*maxPooledWindowSize) + `"`
+	r := AcquireReader(strings.NewReader(large))
+	if _, err := r.NextString(); err != nil {
+		t.Errorf("TestPool:NextString:err=%s", err.Error())
+	}
+	if cap(r.window) <= maxPooledWindowSize {
+		t.Errorf("TestPool:window=%d", cap(r.window))
+	}
+	ReleaseReader(r)
+	for i := 0; i < 3; i++ {
+		r := AcquireReader(strings.NewReader(`1`))
+		if cap(r.window) > maxPooledWindowSize {
+			t.Errorf("TestPool:AcquireReader:window=%d", cap(r.window))
+		}
+		ReleaseReader(r)
+	}
+
+	data := []byte(`"hello, world"`)
+	input := bytes.NewReader(data)
+	r = NewReader(input)
+	if allocs := testing.AllocsPerRun(100, func() {
+		input.Reset(data)
+		r.Reset(input)
+		if _, err := r.NextString(); err != nil {
+			t.Errorf("TestPool:NextString:err=%s", err.Error())
+		}
+	}); allocs > 1 {
+		t.Errorf("TestPool:Reset:allocs=%g", allocs)
+	}
+}
@@ -10,9 +10,12 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"math"
+	"math/big"
 	"strconv"
+	"unicode/utf8"
 )
 
 // A structure, name or value type in a JSON-encoded string.
@@ -48,11 +51,89 @@ var (
 	InvalidInput    = errors.New("rgo: Invalid input")
 )
 
+// A SyntaxError describes malformed input and where it was found.  It
+// matches InvalidInput with errors.Is, as well as io.ErrUnexpectedEOF if
+// the input ended early.
+type SyntaxError struct {
+	// The offset of the unexpected input, counting from zero.
+	Offset int64
+	// The line and byte column of the unexpected input, counting from one.
+	Line, Column int
+	// The unexpected input, which is empty if the input ended early.
+	Found []byte
+	// A description of what was expected instead.
+	Expected string
+	// InvalidInput, or io.ErrUnexpectedEOF if the input ended early.
+	Err error
+}
+
+func (e *SyntaxError) Error() string {
+	found := "end of input"
+	if len(e.Found) > 0 {
+		found = strconv.Quote(string(e.Found))
+	}
+	return fmt.Sprintf("rgo: Invalid input at line %d, column %d (offset %d): found %s, expected %s", e.Line, e.Column, e.Offset, found, e.Expected)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+func (e *SyntaxError) Is(target error) bool {
+	return target == InvalidInput
+}
+
+// A RangeError describes a number that is out of range for the type it
+// was read as.  It matches strconv.ErrRange with errors.Is.
+type RangeError struct {
+	// The JSONPath of the number.
+	Path string
+	// The number as it appeared in the input.
+	Number string
+	// The type that the number was read as, such as "uint8".
+	Type string
+}
+
+func (e *RangeError) Error() string {
+	return "rgo: " + e.Number + " out of range for " + e.Type + " at " + e.Path
+}
+
+func (e *RangeError) Unwrap() error {
+	return strconv.ErrRange
+}
+
+// The state of a document, array or object being read or written.
+type scope int
+
+const (
+	// An array with no elements.
+	emptyArray scope = iota
+	// An array with at least one element.
+	nonemptyArray
+	// An object with no names.
+	emptyObject
+	// An object whose most recent name has no value yet.
+	danglingName
+	// An object with at least one name and value.
+	nonemptyObject
+	// A document with no top-level value yet.
+	emptyDocument
+	// A document with at least one top-level value.
+	nonemptyDocument
+	// A document that has been closed.
+	closedDocument
+)
+
 // Write a JSON (RFC 4627) encoded value to a Writer, one token at a time.
+// Only one top-level value may be written, unless the DocumentMode is
+// JSONLines or JSONSequence.
 type Writer struct {
-	w            io.Writer
-	pendingComma bool
-	buf          [32]byte
+	w      io.Writer
+	stack  []scope
+	mode   DocumentMode
+	prefix string
+	indent string
+	buf    [32]byte
 }
 
 func (w *Writer) writeByte(b byte) error {
@@ -63,16 +144,90 @@ func (w *Writer) writeByte(b byte) error {
 
 // Create a new instance that writes a JSON-encoded stream to w.
 func NewWriter(w io.Writer) *Writer {
-	return &Writer{w: w}
+	return &Writer{w: w, stack: []scope{emptyDocument}}
+}
+
+// Discard all state and configuration, and write to w, as if created by
+// NewWriter(w).
+func (w *Writer) Reset(writer io.Writer) {
+	*w = Writer{w: writer, stack: append(w.stack[:0], emptyDocument)}
+}
+
+// Configure the writer to start each array element and object name on a
+// new line, beginning with prefix followed by one copy of indent for each
+// level of nesting, and to write ": " between names and values.  Empty
+// arrays and objects are still written as [] and {}.  If prefix and indent
+// are both empty, which is the default, the output is compact.
+func (w *Writer) SetIndent(prefix, indent string) {
+	w.prefix = prefix
+	w.indent = indent
+}
+
+// Configure how top-level values are separated.  With JSONSequence, each
+// top-level value is written as a record of an RFC 7464 JSON text
+// sequence, with RS (0x1E) before it and LF after it.  With JSONLines,
+// each top-level value is followed by LF, and SetIndent should not be
+// used.  Otherwise, which is the default, writing a second top-level
+// value returns IllegalState.
+func (w *Writer) SetDocumentMode(mode DocumentMode) {
+	w.mode = mode
+}
+
+func (w *Writer) pretty() bool {
+	return w.prefix != "" || w.indent != ""
+}
+
+// Start a new line indented for the current nesting.
+func (w *Writer) newline() error {
+	if err := w.writeByte('\n'); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w.w, w.prefix); err != nil {
+		return err
+	}
+	for i := 1; i < len(w.stack); i++ {
+		if _, err := io.WriteString(w.w, w.indent); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+// Check that a value may be written in the current scope, and write
+// the separator that precedes it.
 func (w *Writer) beginValue() error {
-	if w.pendingComma {
+	top := len(w.stack) - 1
+	switch w.stack[top] {
+	case emptyDocument, nonemptyDocument:
+		if w.stack[top] == nonemptyDocument && w.mode != JSONSequence && w.mode != JSONLines {
+			return IllegalState
+		}
+		if w.mode == JSONSequence {
+			if err := w.writeByte(recordSeparator); err != nil {
+				return err
+			}
+		}
+		w.stack[top] = nonemptyDocument
+	case emptyArray:
+		w.stack[top] = nonemptyArray
+		if w.pretty() {
+			if err := w.newline(); err != nil {
+				return err
+			}
+		}
+	case nonemptyArray:
 		if err := w.writeByte(','); err != nil {
 			return err
 		}
-	} else {
-		w.pendingComma = true
+		if w.pretty() {
+			if err := w.newline(); err != nil {
+				return err
+			}
+		}
+	case danglingName:
+		w.stack[top] = nonemptyObject
+	default:
+		return IllegalState
 	}
 	return nil
 }
@@ -82,7 +237,7 @@ func (w *Writer) BeginArray() error {
 	if err := w.beginValue(); err != nil {
 		return err
 	}
-	w.pendingComma = false
+	w.stack = append(w.stack, emptyArray)
 	if err := w.writeByte('['); err != nil {
 		return err
 	}
@@ -94,7 +249,7 @@ func (w *Writer) BeginObject() error {
 	if err := w.beginValue(); err != nil {
 		return err
 	}
-	w.pendingComma = false
+	w.stack = append(w.stack, emptyObject)
 	if err := w.writeByte('{'); err != nil {
 		return err
 	}
@@ -103,19 +258,48 @@ func (w *Writer) BeginObject() error {
 
 // End encoding the current array.
 func (w *Writer) EndArray() error {
-	w.pendingComma = true
-	if err := w.writeByte(']'); err != nil {
-		return err
-	}
-	return nil
+	return w.endContainer(emptyArray, nonemptyArray, ']')
 }
 
 // End encoding the current object.
 func (w *Writer) EndObject() error {
-	w.pendingComma = true
-	if err := w.writeByte('}'); err != nil {
+	return w.endContainer(emptyObject, nonemptyObject, '}')
+}
+
+func (w *Writer) endContainer(empty, nonempty scope, end byte) error {
+	context := w.stack[len(w.stack)-1]
+	if context != empty && context != nonempty {
+		return IllegalState
+	}
+	w.stack = w.stack[:len(w.stack)-1]
+	if context == nonempty && w.pretty() {
+		if err := w.newline(); err != nil {
+			return err
+		}
+	}
+	if err := w.writeByte(end); err != nil {
 		return err
 	}
+	return w.endValue()
+}
+
+// Finish writing a value, ending the record if it is a top-level value
+// and the DocumentMode is JSONSequence or JSONLines.
+func (w *Writer) endValue() error {
+	if len(w.stack) == 1 && (w.mode == JSONSequence || w.mode == JSONLines) {
+		return w.writeByte('\n')
+	}
+	return nil
+}
+
+// Check that a complete top-level value has been written and that all
+// arrays and objects have been ended.  Writing after closing returns
+// IllegalState.  The underlying io.Writer is not closed.
+func (w *Writer) Close() error {
+	if len(w.stack) > 1 || w.stack[0] != nonemptyDocument {
+		return IllegalState
+	}
+	w.stack[0] = closedDocument
 	return nil
 }
 
@@ -264,11 +448,21 @@ func writeQuotedString(w *Writer, s string) error {
 
 // Encode the property name.
 func (w *Writer) Name(name string) error {
-	if w.pendingComma {
+	top := len(w.stack) - 1
+	switch w.stack[top] {
+	case emptyObject:
+	case nonemptyObject:
 		if err := w.writeByte(','); err != nil {
 			return err
 		}
-		w.pendingComma = false
+	default:
+		return IllegalState
+	}
+	w.stack[top] = danglingName
+	if w.pretty() {
+		if err := w.newline(); err != nil {
+			return err
+		}
 	}
 	if err := writeQuotedString(w, name); err != nil {
 		return err
@@ -276,6 +470,11 @@ func (w *Writer) Name(name string) error {
 	if err := w.writeByte(':'); err != nil {
 		return err
 	}
+	if w.pretty() {
+		if err := w.writeByte(' '); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -287,7 +486,7 @@ func (w *Writer) NullValue() error {
 	if _, err := io.WriteString(w.w, "null"); err != nil {
 		return err
 	}
-	return nil
+	return w.endValue()
 }
 
 // Encode value.
@@ -318,7 +517,7 @@ func (w *Writer) Int64Value(value int64) error {
 	if _, err := w.w.Write(strconv.AppendInt(w.buf[:0], value, 10)); err != nil {
 		return err
 	}
-	return nil
+	return w.endValue()
 }
 
 // Encode value.
@@ -349,7 +548,7 @@ func (w *Writer) Uint64Value(value uint64) error {
 	if _, err := w.w.Write(strconv.AppendUint(w.buf[:0], value, 10)); err != nil {
 		return err
 	}
-	return nil
+	return w.endValue()
 }
 
 // Encode value.
@@ -372,7 +571,7 @@ func (w *Writer) floatValue(value float64, bitSize int) error {
 	if _, err := w.w.Write(strconv.AppendFloat(w.buf[:0], value, 'g', -1, bitSize)); err != nil {
 		return err
 	}
-	return nil
+	return w.endValue()
 }
 
 // Encode value.
@@ -389,7 +588,7 @@ func (w *Writer) BoolValue(value bool) error {
 			return err
 		}
 	}
-	return nil
+	return w.endValue()
 }
 
 // Encode value.
@@ -400,7 +599,22 @@ func (w *Writer) StringValue(value string) error {
 	if err := writeQuotedString(w, value); err != nil {
 		return err
 	}
-	return nil
+	return w.endValue()
+}
+
+// Encode value, which is written exactly as given.  Returns
+// IllegalArgument if value is not a number as specified by RFC 4627.
+func (w *Writer) NumberValue(value Number) error {
+	if !validNumber([]byte(value)) {
+		return IllegalArgument
+	}
+	if err := w.beginValue(); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w.w, string(value)); err != nil {
+		return err
+	}
+	return w.endValue()
 }
 
 // Encode value.
@@ -436,206 +650,1347 @@ func (w *Writer) Value(value interface{}) error {
 		return w.BoolValue(v)
 	case string:
 		return w.StringValue(v)
+	case Number:
+		return w.NumberValue(v)
+	case *big.Int:
+		if v == nil {
+			return w.NullValue()
+		}
+		return w.NumberValue(Number(v.String()))
+	case *big.Float:
+		if v == nil {
+			return w.NullValue()
+		} else if v.IsInf() {
+			return IllegalArgument
+		}
+		return w.NumberValue(Number(v.Text('g', -1)))
+	case *big.Rat:
+		if v == nil {
+			return w.NullValue()
+		}
+		s, ok := ratString(v)
+		if !ok {
+			return IllegalArgument
+		}
+		return w.NumberValue(Number(s))
 	default:
 		return IllegalArgument
 	}
 }
 
-// Read a JSON (RFC 4627) encoded value as a stream of tokens.
+// Read a JSON (RFC 4627) encoded value as a stream of tokens.  Once
+// reading a token fails, such as with InvalidInput, every method that
+// reads a token returns the same error until Recover or Reset is called,
+// or NextDocument when the DocumentMode is JSONSequence.
 type Reader struct {
-	r       *bufio.Reader
+	src     io.Reader
+	t       *transcoder
 	token   Token
 	value   bytes.Buffer
-	hasNext bool
+	lenient bool
+	policy  UTF8Policy
+	mode    DocumentMode
+	limits  Limits
+	tokens  int64
+	stack   []scope
+	path    []pathElement
+
+	// The record delimiter used by Recover, if set.
+	delimiter    byte
+	hasDelimiter bool
+	// The offset of the start of the current record.
+	recordStart int64
+
+	offset        int64
+	line          int
+	lineStart     int64
+	prevLineStart int64
+
+	// The input is read from data, which is either the whole input when
+	// reading from a byte slice, or a window over the input that is
+	// refilled from src.  buf is the part of data that is within
+	// Limits.MaxTotalBytes.  The bytes from mark, if it is not negative,
+	// are kept in the window when it is refilled, and all indexes into it
+	// are adjusted.
+	data   []byte
+	buf    []byte
+	pos    int
+	mark   int
+	window []byte
+	srcErr error
+
+	// If hasSub is set, the text of the current token is sub, which is
+	// buf[subStart:subEnd] as it is read, rather than value.
+	sub              []byte
+	hasSub           bool
+	subStart, subEnd int
+
+	// The current token is buf[rawStart:rawEnd].  While capturing, the
+	// value being captured starts at captureStart.
+	rawStart     int
+	rawEnd       int
+	capturing    bool
+	captureStart int
+
+	// The tokens that have been read ahead of the current token.
+	lookahead []lookaheadToken
+
+	// The unused part of the block from which copies are taken.
+	block []byte
+
+	// The error that ended the last sequence from Elements or Members.
+	err error
+	// The error that stopped reading tokens, which is returned for every
+	// token until the document is reset.
+	failed error
+}
+
+// A token that has been read ahead by PeekN.
+type lookaheadToken struct {
+	token      Token
+	value      []byte
+	start, end int
+}
+
+// An array or object that has been entered by a Reader.
+type pathElement struct {
+	object bool
+	index  int
+	name   string
+	// Set instead of name by NextNameBytes.
+	nameBytes []byte
 }
 
-// Create a new instance that reads a JSON-encoded stream from r.
+// The buffer size used by NewReader.
+const defaultBufferSize = 4096
+
+// The smallest buffer size used by NewReaderSize.
+const minBufferSize = 16
+
+// Create a new instance that reads a JSON-encoded stream from r.  The
+// stream may be encoded in UTF-8, UTF-16 or UTF-32, which is detected from
+// its first bytes, and is transcoded to UTF-8 as it is read.  Offsets in
+// errors count bytes of UTF-8.
 func NewReader(r io.Reader) *Reader {
-	return &Reader{r: bufio.NewReaderSize(r, 6)}
+	return NewReaderSize(r, defaultBufferSize)
 }
 
-func (r *Reader) skipWhitespace() error {
-	for {
-		b, err := r.r.ReadByte()
-		if err != nil {
-			return err
+// Create a new instance that reads a JSON-encoded stream from r, as
+// NewReader does, with a buffer of at least size bytes.  The buffer grows
+// as needed to hold a token, or a value read by NextRawValue, that is
+// larger than size.
+func NewReaderSize(r io.Reader, size int) *Reader {
+	if size < minBufferSize {
+		size = minBufferSize
+	}
+	reader := &Reader{window: make([]byte, size)}
+	reader.Reset(r)
+	return reader
+}
+
+// Create a new instance that reads a JSON-encoded stream from b, indexing
+// it directly instead of copying it through a buffer.  Strings, names and
+// numbers that need no unescaping are returned by NextNameBytes and
+// NextStringBytes as sub-slices of b, so b must not be modified while it
+// is in use.  UTF-16 and UTF-32 input is detected as with NewReader, and
+// is transcoded to a new slice.
+func NewBytesReader(b []byte) *Reader {
+	r := &Reader{}
+	r.ResetBytes(b)
+	return r
+}
+
+// Discard all state and configuration, and read from rd, as if created by
+// NewReader(rd), reusing the internal buffers.
+func (r *Reader) Reset(rd io.Reader) {
+	if r.t == nil {
+		r.t = newTranscoder(rd)
+	} else {
+		r.t.reset(rd)
+	}
+	if r.window == nil {
+		r.window = make([]byte, defaultBufferSize)
+	}
+	r.reset()
+	r.src = r.t
+	r.data = r.window[:0]
+	r.buf = r.data
+}
+
+// Discard all state and configuration, and read from b, as if created by
+// NewBytesReader(b), reusing the internal buffers.
+func (r *Reader) ResetBytes(b []byte) {
+	enc, bom, _ := detectEncoding(b, true)
+	if enc == utf8Encoding {
+		b = b[bom:]
+	} else {
+		b, _ = io.ReadAll(newTranscoder(bytes.NewReader(b)))
+	}
+	if r.t != nil {
+		// Release the previous input.
+		r.t.reset(nil)
+	}
+	r.reset()
+	r.data = b
+	r.buf = b
+}
+
+func (r *Reader) reset() {
+	value := r.value
+	value.Reset()
+	for i := range r.lookahead {
+		r.lookahead[i] = lookaheadToken{}
+	}
+	for i := range r.path {
+		r.path[i] = pathElement{}
+	}
+	*r = Reader{
+		t:         r.t,
+		window:    r.window,
+		block:     r.block,
+		value:     value,
+		stack:     append(r.stack[:0], emptyDocument),
+		path:      r.path[:0],
+		lookahead: r.lookahead[:0],
+		mark:      -1,
+	}
+}
+
+// Configure this parser to be liberal in what it accepts.  By default,
+// this parser is strict and only accepts JSON as specified by RFC 4627.
+// Setting the parser to lenient causes it to accept:
+//   - End of line comments starting with // or # and ending with a newline.
+//   - C-style comments starting with /* and ending with */.
+//   - Names and strings that are unquoted or 'single quoted'.
+//   - Names separated from values by = or => instead of :.
+//   - Array elements and object members separated by ; instead of ,.
+//   - Unnecessary array separators, which are interpreted as null values.
+//   - Numbers that are NaN, Infinity or -Infinity.
+//   - Streams that start with the non-execute prefix, ")]}'\n".
+func (r *Reader) SetLenient(lenient bool) {
+	r.lenient = lenient
+}
+
+// Return true if this parser is liberal in what it accepts.
+func (r *Reader) IsLenient() bool {
+	return r.lenient
+}
+
+// How a Reader handles invalid UTF-8 in strings and names.
+type UTF8Policy int
+
+const (
+	// Fail with a SyntaxError.
+	RejectInvalidUTF8 UTF8Policy = iota
+	// Replace each invalid sequence with U+FFFD.
+	ReplaceInvalidUTF8
+	// Pass invalid sequences through unchanged.
+	PassInvalidUTF8
+)
+
+// Configure how invalid UTF-8 in strings and names, including encoded
+// surrogate halves, is handled.  The default is RejectInvalidUTF8.
+func (r *Reader) SetUTF8Policy(policy UTF8Policy) {
+	r.policy = policy
+}
+
+// How a Reader separates the top-level values in its input.
+type DocumentMode int
+
+const (
+	// Top-level values are read one after another as a single stream,
+	// which is followed by END_DOCUMENT at the end of the input.
+	ValueStream DocumentMode = iota
+	// Each top-level value is a document, which is followed by
+	// END_DOCUMENT.  NextDocument moves to the next document.
+	MultipleDocuments
+	// As MultipleDocuments, but each document must be on a line of its
+	// own, as in JSON Lines.  Lines may end with \n or \r\n, and blank
+	// lines are not allowed.
+	JSONLines
+	// As MultipleDocuments, but each document is a record of an RFC 7464
+	// JSON text sequence, starting with RS (0x1E).  Empty records are
+	// ignored.  A top-level number, true, false or null that is not
+	// followed by whitespace is considered truncated.  NextDocument skips
+	// the rest of the current record, so that reading can continue after
+	// a malformed or truncated record.
+	JSONSequence
+)
+
+// The byte that starts each record of a JSON text sequence.
+const recordSeparator = 0x1e
+
+// Configure how top-level values are separated.  The default is
+// ValueStream.
+func (r *Reader) SetDocumentMode(mode DocumentMode) {
+	r.mode = mode
+}
+
+// Configure the byte that ends each record, up to which Recover discards
+// input.  By default, it is RS (0x1E) when the DocumentMode is
+// JSONSequence, and otherwise a newline.  A delimiter that is set may also
+// separate top-level values, as whitespace does.
+func (r *Reader) SetRecordDelimiter(delimiter byte) {
+	r.delimiter = delimiter
+	r.hasDelimiter = true
+}
+
+// Return true if b was set by SetRecordDelimiter.
+func (r *Reader) isDelimiter(b byte) bool {
+	return r.hasDelimiter && b == r.delimiter
+}
+
+func (r *Reader) recordDelimiter() byte {
+	if r.hasDelimiter {
+		return r.delimiter
+	} else if r.mode == JSONSequence {
+		return recordSeparator
+	}
+	return '\n'
+}
+
+// Configure the limits on the input, which are enforced by every method
+// that reads it, including SkipValue.  By default, there are no limits.
+func (r *Reader) SetLimits(limits Limits) {
+	r.limits = limits
+	r.clip()
+}
+
+// Return a JSONPath to the current location in the JSON value, such as
+// $.tree.kids[3].name.
+func (r *Reader) Path() string {
+	return formatPath(r.path)
+}
+
+func formatPath(path []pathElement) string {
+	buf := []byte{'$'}
+	for _, e := range path {
+		if e.object {
+			buf = append(buf, '.')
+			buf = append(buf, e.name...)
+			buf = append(buf, e.nameBytes...)
+		} else {
+			buf = append(buf, '[')
+			buf = strconv.AppendInt(buf, int64(e.index), 10)
+			buf = append(buf, ']')
 		}
-		switch b {
-		case 0x20, 0x09, 0x0a, 0x0d:
-		default:
-			return r.r.UnreadByte()
+	}
+	return string(buf)
+}
+
+func (r *Reader) pushPath(object bool) {
+	r.path = append(r.path, pathElement{object: object})
+}
+
+func (r *Reader) popPath() {
+	if len(r.path) > 0 {
+		r.path = r.path[:len(r.path)-1]
+	}
+	r.endValue()
+}
+
+// Advance the path past a consumed value.
+func (r *Reader) endValue() {
+	if len(r.path) > 0 {
+		r.path[len(r.path)-1].index++
+	}
+}
+
+func (r *Reader) readByte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		if err := r.more(); err != nil {
+			return 0, err
 		}
 	}
+	b := r.buf[r.pos]
+	r.pos++
+	r.offset++
+	if b == '\n' {
+		r.line++
+		r.prevLineStart = r.lineStart
+		r.lineStart = r.offset
+	}
+	return b, nil
 }
 
-func (r *Reader) skipLiteral(literal string) error {
-	for _, ch := range []byte(literal) {
-		if b, err := r.r.ReadByte(); err != nil {
+func (r *Reader) unreadByte() error {
+	if r.pos == 0 {
+		return bufio.ErrInvalidUnreadByte
+	}
+	r.pos--
+	r.offset--
+	if r.offset < r.lineStart {
+		r.line--
+		r.lineStart = r.prevLineStart
+	}
+	return nil
+}
+
+// Make more bytes available in buf after r.pos, returning io.EOF at the
+// end of the input, or a SizeLimitError at Limits.MaxTotalBytes.
+func (r *Reader) more() error {
+	if len(r.buf) == len(r.data) && r.src != nil {
+		available := len(r.buf) - r.pos
+		err := r.fill()
+		r.clip()
+		if err != nil {
 			return err
-		} else if b != ch {
-			return InvalidInput
+		} else if len(r.buf)-r.pos > available {
+			return nil
 		}
 	}
-	b, err := r.r.ReadByte()
-	if err != nil {
-		if err == io.EOF {
-			r.hasNext = false
+	if len(r.buf) < len(r.data) {
+		return &SizeLimitError{Offset: r.limits.MaxTotalBytes, Limit: r.limits.MaxTotalBytes}
+	}
+	return io.EOF
+}
+
+// Read at least one more byte from src into the window, first discarding
+// the bytes before the mark and before the last byte read, and growing
+// the window if it is more than half full.
+func (r *Reader) fill() error {
+	if r.srcErr != nil {
+		err := r.srcErr
+		r.srcErr = nil
+		return err
+	}
+	keep := r.pos - 1
+	if r.mark >= 0 && r.mark < keep {
+		keep = r.mark
+	}
+	if keep > 0 {
+		r.data = r.window[:copy(r.window, r.data[keep:])]
+		r.shift(keep)
+	}
+	if len(r.data) > len(r.window)/2 {
+		window := make([]byte, 2*len(r.window))
+		r.data = window[:copy(window, r.data)]
+		r.window = window
+	}
+	for i := 0; i < 100; i++ {
+		n, err := r.src.Read(r.window[len(r.data):])
+		r.data = r.window[:len(r.data)+n]
+		if n > 0 {
+			if err != io.EOF {
+				r.srcErr = err
+			}
 			return nil
+		} else if err != nil {
+			return err
 		}
-		return err
 	}
-	switch b {
-	case 0x20, 0x09, 0x0a, 0x0d:
-		return r.readTokenEnd()
-	case ',':
-		r.hasNext = true
-		return nil
-	case ']', '}':
-		r.hasNext = false
-		return r.r.UnreadByte()
-	default:
-		return InvalidInput
+	return io.ErrNoProgress
+}
+
+// Adjust the indexes into buf after the first k bytes have been
+// discarded.
+func (r *Reader) shift(k int) {
+	r.pos -= k
+	if r.mark >= 0 {
+		r.mark -= k
+	}
+	r.rawStart -= k
+	r.rawEnd -= k
+	r.subStart -= k
+	r.subEnd -= k
+	r.captureStart -= k
+	for i := range r.lookahead {
+		r.lookahead[i].start -= k
+		r.lookahead[i].end -= k
+	}
+}
+
+// Set buf to the bytes of data that are within Limits.MaxTotalBytes.
+func (r *Reader) clip() {
+	r.buf = r.data
+	if max := r.limits.MaxTotalBytes; max > 0 {
+		end := max - (r.offset - int64(r.pos))
+		if end < int64(r.pos) {
+			end = int64(r.pos)
+		}
+		if end < int64(len(r.data)) {
+			r.buf = r.data[:end]
+		}
+	}
+}
+
+// Start the text of a value or name at the byte that has just been read,
+// keeping it in the window until the next token is read.
+func (r *Reader) startToken() {
+	r.rawStart = r.pos - 1
+	if r.mark < 0 {
+		r.mark = r.rawStart
+	}
+}
+
+// Return a SyntaxError for the found bytes, which have just been read.
+// A record delimiter that ends a malformed record is unread, so that the
+// next record can be read after Recover.
+func (r *Reader) unexpected(expected string, found ...byte) error {
+	err := r.syntaxError(r.offset-int64(len(found)), found, expected, InvalidInput)
+	if len(found) > 0 && found[len(found)-1] == r.recordDelimiter() {
+		if err := r.unreadByte(); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// Return a SyntaxError for input that ended early.
+func (r *Reader) unexpectedEOF(expected string) error {
+	return r.syntaxError(r.offset, nil, expected, io.ErrUnexpectedEOF)
+}
+
+func (r *Reader) syntaxError(offset int64, found []byte, expected string, err error) error {
+	line, lineStart := r.line+1, r.lineStart
+	if offset < lineStart {
+		line, lineStart = r.line, r.prevLineStart
+	}
+	return &SyntaxError{
+		Offset:   offset,
+		Line:     line,
+		Column:   int(offset-lineStart) + 1,
+		Found:    found,
+		Expected: expected,
+		Err:      err,
+	}
+}
+
+// Return the next byte that is not whitespace or, when lenient, part of a
+// comment.
+func (r *Reader) nextNonWhitespace() (byte, error) {
+	for {
+		for r.pos < len(r.buf) {
+			if b := r.buf[r.pos]; b != 0x20 && b != 0x09 && b != 0x0d {
+				break
+			}
+			r.pos++
+			r.offset++
+		}
+		if r.pos < len(r.buf) {
+			if b := r.buf[r.pos]; b > 0x20 && b != '#' && b != '/' && (len(r.stack) > 1 || !r.isDelimiter(b)) {
+				r.pos++
+				r.offset++
+				return b, nil
+			}
+		}
+		b, err := r.readByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case 0x20, 0x09, 0x0d:
+		case 0x0a:
+			if r.mode == JSONLines {
+				return 0, r.unexpected("record on one line", b)
+			}
+		case '#':
+			if !r.lenient {
+				return b, nil
+			}
+			if err := r.skipToEndOfLine(); err != nil {
+				return 0, err
+			}
+		case '/':
+			if !r.lenient {
+				return b, nil
+			}
+			b, err := r.readByte()
+			if err != nil {
+				if err == io.EOF {
+					return 0, r.unexpectedEOF("comment")
+				}
+				return 0, err
+			}
+			switch b {
+			case '/':
+				if err := r.skipToEndOfLine(); err != nil {
+					return 0, err
+				}
+			case '*':
+				if err := r.skipBlockComment(); err != nil {
+					return 0, err
+				}
+			default:
+				return 0, r.unexpected("comment", '/', b)
+			}
+		default:
+			if len(r.stack) == 1 && r.isDelimiter(b) && r.mode != JSONSequence {
+				continue
+			}
+			return b, nil
+		}
 	}
 }
 
-func (r *Reader) readTokenEnd() error {
+func (r *Reader) skipToEndOfLine() error {
 	for {
-		b, err := r.r.ReadByte()
+		b, err := r.readByte()
 		if err != nil {
 			if err == io.EOF {
-				r.hasNext = false
 				return nil
 			}
 			return err
 		}
-		switch b {
-		case 0x20, 0x09, 0x0a, 0x0d:
-		case ',':
-			r.hasNext = true
+		if b == '\n' || b == '\r' {
 			return nil
-		default:
-			r.hasNext = false
-			return r.r.UnreadByte()
 		}
 	}
 }
 
-func (r *Reader) readContainerStart(containerEnd byte) error {
+func (r *Reader) skipBlockComment() error {
+	star := false
 	for {
-		b, err := r.r.ReadByte()
+		b, err := r.readByte()
 		if err != nil {
 			if err == io.EOF {
-				return io.ErrUnexpectedEOF
+				return r.unexpectedEOF("'*/'")
 			}
 			return err
 		}
-		switch b {
-		case 0x20, 0x09, 0x0a, 0x0d:
-		default:
-			r.hasNext = b != containerEnd
-			return r.r.UnreadByte()
+		if star && b == '/' {
+			return nil
 		}
+		star = b == '*'
 	}
 }
 
-func (r *Reader) readToken(skipValue bool) error {
-	if r.token != NO_TOKEN {
-		panic("rgo: Internal error")
-	}
-	if err := r.skipWhitespace(); err != nil {
+// Skip the non-execute prefix, ")]}'\n", if the stream starts with it.
+func (r *Reader) consumeNonExecutePrefix() error {
+	const prefix = ")]}'\n"
+	if b, err := r.nextNonWhitespace(); err != nil {
 		if err == io.EOF {
-			r.token = END_DOCUMENT
 			return nil
 		}
 		return err
+	} else if b != prefix[0] {
+		return r.unreadByte()
 	}
-	b, err := r.r.ReadByte()
-	if err != nil {
+	for i := 1; i < len(prefix); i++ {
+		if b, err := r.readByte(); err != nil {
+			if err == io.EOF {
+				return r.unexpectedEOF("non-execute prefix")
+			}
+			return err
+		} else if b != prefix[i] {
+			return r.unexpected("non-execute prefix", b)
+		}
+	}
+	return nil
+}
+
+// Read the rest of literal, whose first byte has been read.
+func (r *Reader) skipLiteral(literal string) error {
+	for _, ch := range []byte(literal[1:]) {
+		if b, err := r.readByte(); err != nil {
+			if err == io.EOF {
+				return r.unexpectedEOF(strconv.Quote(literal))
+			}
+			return err
+		} else if b != ch {
+			return r.unexpected(strconv.Quote(literal), b)
+		}
+	}
+	return r.readValueEnd("end of literal")
+}
+
+// Check that a value is not immediately followed by literal characters.
+func (r *Reader) readValueEnd(expected string) error {
+	if r.pos >= len(r.buf) {
+		if err := r.more(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+	b := r.buf[r.pos]
+	if b == recordSeparator && r.mode == JSONSequence {
+		// A string may be followed directly by the next record.
+		if r.token != STRING && r.token != NAME {
+			r.readByte()
+			return r.unexpected("whitespace after possibly truncated value", b)
+		}
+	} else if isLiteral(b) && !r.isDelimiter(b) {
+		r.readByte()
+		return r.unexpected(expected, b)
+	}
+	return nil
+}
+
+// Check that the input does not end immediately after the start of an
+// array or object.
+func (r *Reader) readContainerStart() error {
+	if _, err := r.nextNonWhitespace(); err != nil {
 		if err == io.EOF {
-			r.token = END_DOCUMENT
+			return r.unexpectedEOF("value or end of container")
+		}
+		return err
+	}
+	return r.unreadByte()
+}
+
+func (r *Reader) readToken(skipValue bool) error {
+	offset := r.offset
+	if !r.capturing && len(r.lookahead) == 0 {
+		r.mark = -1
+	}
+	r.hasSub = false
+	if err := r.scanToken(skipValue); err != nil {
+		return r.fail(err)
+	}
+	r.rawEnd = r.pos
+	if r.hasSub {
+		// Limit the capacity, so that appending to the value returned
+		// by NextNameBytes or NextStringBytes cannot overwrite the input.
+		r.sub = r.buf[r.subStart:r.subEnd:r.subEnd]
+	}
+	if r.token != END_DOCUMENT {
+		r.tokens++
+		if r.limits.MaxTokens > 0 && r.tokens > r.limits.MaxTokens {
+			return r.fail(&TokenLimitError{Offset: offset, Limit: r.limits.MaxTokens})
+		}
+	}
+	return nil
+}
+
+// Discard the token that was being read when err occurred, which may have
+// been partly set, and return err for it and every later token until the
+// document is reset.
+func (r *Reader) fail(err error) error {
+	r.token = NO_TOKEN
+	r.failed = err
+	return err
+}
+
+func (r *Reader) scanToken(skipValue bool) error {
+	if r.token != NO_TOKEN {
+		panic("rgo: Internal error")
+	}
+	top := len(r.stack) - 1
+	scope := r.stack[top]
+	switch scope {
+	case emptyArray:
+		r.stack[top] = nonemptyArray
+	case nonemptyArray:
+		b, err := r.nextNonWhitespace()
+		if err != nil {
+			if err == io.EOF {
+				return r.unexpectedEOF("',' or ']'")
+			}
+			return err
+		}
+		switch b {
+		case ']':
+			r.token = END_ARRAY
+			r.stack = r.stack[:top]
 			return nil
+		case ',':
+		case ';':
+			if !r.lenient {
+				return r.unexpected("',' or ']'", b)
+			}
+		default:
+			return r.unexpected("',' or ']'", b)
+		}
+	case emptyObject, nonemptyObject:
+		return r.readName(skipValue, top, scope)
+	case danglingName:
+		r.stack[top] = nonemptyObject
+		b, err := r.nextNonWhitespace()
+		if err != nil {
+			if err == io.EOF {
+				return r.unexpectedEOF("':'")
+			}
+			return err
+		}
+		switch b {
+		case ':':
+		case '=':
+			if !r.lenient {
+				return r.unexpected("':'", b)
+			}
+			if b, err := r.readByte(); err != nil {
+				if err != io.EOF {
+					return err
+				}
+			} else if b != '>' {
+				if err := r.unreadByte(); err != nil {
+					return err
+				}
+			}
+		default:
+			return r.unexpected("':'", b)
+		}
+	case emptyDocument:
+		r.stack[top] = nonemptyDocument
+		r.recordStart = r.offset
+		if r.mode == JSONSequence {
+			if err := r.readRecordStart(); err != nil {
+				return err
+			}
+		} else if r.lenient && r.offset == 0 {
+			if err := r.consumeNonExecutePrefix(); err != nil {
+				return err
+			}
+		}
+	case nonemptyDocument:
+		if r.mode != ValueStream {
+			return r.endDocument()
+		}
+		// Another top-level value may follow, as in a stream of values.
+		r.recordStart = r.offset
+	case closedDocument:
+		r.token = END_DOCUMENT
+		return nil
+	}
+	b, err := r.nextNonWhitespace()
+	if err != nil {
+		if err == io.EOF {
+			if top == 0 {
+				r.token = END_DOCUMENT
+				if r.mode != ValueStream {
+					r.stack[top] = closedDocument
+				}
+				return nil
+			}
+			return r.unexpectedEOF("value")
 		}
 		return err
 	}
+	if top == 0 {
+		// The record starts with its value, after any whitespace.
+		r.recordStart = r.offset - 1
+	}
+	r.startToken()
 	r.value.Reset()
-	r.hasNext = false
 	switch b {
 	case '[':
+		if err := r.checkDepth(); err != nil {
+			return err
+		}
 		r.token = BEGIN_ARRAY
-		return r.readContainerStart(']')
-	case ']':
-		r.token = END_ARRAY
-		return r.readTokenEnd()
+		r.stack = append(r.stack, emptyArray)
+		return r.readContainerStart()
 	case '{':
+		if err := r.checkDepth(); err != nil {
+			return err
+		}
 		r.token = BEGIN_OBJECT
-		return r.readContainerStart('}')
-	case '}':
-		r.token = END_OBJECT
-		return r.readTokenEnd()
+		r.stack = append(r.stack, emptyObject)
+		return r.readContainerStart()
+	case ']':
+		if scope == emptyArray {
+			r.token = END_ARRAY
+			r.stack = r.stack[:top]
+			return nil
+		}
+		fallthrough
+	case ',', ';':
+		// When lenient, a missing array element is a null.
+		if r.lenient && (scope == emptyArray || scope == nonemptyArray) {
+			r.token = NULL
+			return r.unreadByte()
+		}
+		return r.unexpected("value", b)
+	case '"':
+		r.token = STRING
+		return r.readStringOrName(skipValue, b)
+	}
+	if r.lenient {
+		if b == '\'' {
+			r.token = STRING
+			return r.readStringOrName(skipValue, b)
+		}
+		return r.readUnquotedValue(b)
+	}
+	switch b {
 	case 'f':
 		r.token = BOOLEAN
 		if !skipValue {
 			r.value.WriteByte(0)
 		}
-		return r.skipLiteral("alse")
+		return r.skipLiteral("false")
 	case 't':
 		r.token = BOOLEAN
 		if !skipValue {
 			r.value.WriteByte(1)
 		}
-		return r.skipLiteral("rue")
+		return r.skipLiteral("true")
 	case 'n':
 		r.token = NULL
-		return r.skipLiteral("ull")
-	case '"':
-		return r.readStringOrName(skipValue)
-	case '-':
+		return r.skipLiteral("null")
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 		r.token = NUMBER
-		if !skipValue {
-			if err := r.value.WriteByte(b); err != nil {
+		// Numbers never need unescaping.
+		if err := r.readNumber(b == '-', b == '0'); err != nil {
+			return err
+		}
+		r.setSub(skipValue, r.rawStart, r.pos)
+		return nil
+	default:
+		return r.unexpected("value", b)
+	}
+}
+
+// Read the end of a document that has been read completely.
+func (r *Reader) endDocument() error {
+	switch r.mode {
+	case JSONLines:
+		if err := r.readLineEnd(); err != nil {
+			return err
+		}
+	case JSONSequence:
+		if b, err := r.nextNonWhitespace(); err != nil {
+			if err != io.EOF {
+				return err
+			}
+		} else if b != recordSeparator {
+			return r.unexpected("record separator", b)
+		} else if err := r.unreadByte(); err != nil {
+			return err
+		}
+	}
+	r.token = END_DOCUMENT
+	r.stack[0] = closedDocument
+	return nil
+}
+
+// Read the rest of the line that ends a JSON Lines record.
+func (r *Reader) readLineEnd() error {
+	for {
+		b, err := r.readByte()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		switch b {
+		case 0x20, 0x09, 0x0d:
+		case 0x0a:
+			return nil
+		default:
+			return r.unexpected("end of line", b)
+		}
+	}
+}
+
+// Read the record separator that starts a record of a JSON text sequence,
+// skipping any empty records.
+func (r *Reader) readRecordStart() error {
+	for start := true; ; start = false {
+		b, err := r.nextNonWhitespace()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if b != recordSeparator {
+			if start {
+				return r.unexpected("record separator", b)
+			}
+			return r.unreadByte()
+		}
+	}
+}
+
+// Skip to the record separator that starts the next record of a JSON
+// text sequence, or to the end of the input.
+func (r *Reader) skipRecord() error {
+	for {
+		b, err := r.readByte()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if b == recordSeparator {
+			return r.unreadByte()
+		}
+	}
+}
+
+// The nesting depth at which functions that recurse for each level, such
+// as ReadValue and Decode, stop when Limits.MaxDepth is not set, rather
+// than overflowing the stack.
+const maxRecursionDepth = 10000
+
+// Return a DepthLimitError if the next token, which is the start of an
+// array or object, would be nested too deeply for a function that
+// recurses for each level.  Limits.MaxDepth, if it is set, is checked as
+// the token is read instead.
+func (r *Reader) checkRecursionDepth() error {
+	if r.limits.MaxDepth == 0 && len(r.path) >= maxRecursionDepth {
+		return &DepthLimitError{Offset: r.offset - int64(r.pos-r.rawStart), Limit: maxRecursionDepth}
+	}
+	return nil
+}
+
+// Return a DepthLimitError if the array or object that has just been
+// started would exceed Limits.MaxDepth.
+func (r *Reader) checkDepth() error {
+	if r.limits.MaxDepth > 0 && len(r.stack) > r.limits.MaxDepth {
+		return &DepthLimitError{Offset: r.offset - 1, Limit: r.limits.MaxDepth}
+	}
+	return nil
+}
+
+// Read the next name or the end of the object whose scope is at the top
+// of the stack.
+func (r *Reader) readName(skipValue bool, top int, scope scope) error {
+	r.stack[top] = danglingName
+	if scope == nonemptyObject {
+		b, err := r.nextNonWhitespace()
+		if err != nil {
+			if err == io.EOF {
+				return r.unexpectedEOF("',' or '}'")
+			}
+			return err
+		}
+		switch b {
+		case '}':
+			r.token = END_OBJECT
+			r.stack = r.stack[:top]
+			return nil
+		case ',':
+		case ';':
+			if !r.lenient {
+				return r.unexpected("',' or '}'", b)
+			}
+		default:
+			return r.unexpected("',' or '}'", b)
+		}
+	}
+	b, err := r.nextNonWhitespace()
+	if err != nil {
+		if err == io.EOF {
+			return r.unexpectedEOF("name")
+		}
+		return err
+	}
+	r.startToken()
+	r.value.Reset()
+	switch b {
+	case '}':
+		if scope == emptyObject {
+			r.token = END_OBJECT
+			r.stack = r.stack[:top]
+			return nil
+		}
+	case '"':
+		r.token = NAME
+		return r.readStringOrName(skipValue, b)
+	case '\'':
+		if r.lenient {
+			r.token = NAME
+			return r.readStringOrName(skipValue, b)
+		}
+	default:
+		if r.lenient && isLiteral(b) {
+			r.token = NAME
+			return r.readUnquoted(b)
+		}
+	}
+	return r.unexpected("name", b)
+}
+
+func isLiteral(b byte) bool {
+	switch b {
+	case '/', '\\', ';', '#', '=', '{', '}', '[', ']', ':', ',', 0x20, 0x09, 0x0c, 0x0d, 0x0a:
+		return false
+	default:
+		return true
+	}
+}
+
+// Read an unquoted name, string, number or keyword, given its first
+// byte.  Only used when lenient.
+func (r *Reader) readUnquoted(b byte) error {
+	start := r.offset - 1
+	for {
+		if max := r.limits.MaxStringBytes; max > 0 && r.offset-start > int64(max) {
+			return &StringLimitError{Offset: start, Limit: max}
+		}
+		if b >= 0x80 && r.policy != PassInvalidUTF8 {
+			if err := r.readUTF8(false, b); err != nil {
 				return err
 			}
+		} else if err := r.value.WriteByte(b); err != nil {
+			return err
 		}
-		return r.readNumber(skipValue, true, false)
-	case '0':
+		var err error
+		b, err = r.readByte()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if !isLiteral(b) {
+			return r.unreadByte()
+		}
+	}
+}
+
+func (r *Reader) readUnquotedValue(b byte) error {
+	if !isLiteral(b) {
+		return r.unexpected("value", b)
+	}
+	if err := r.readUnquoted(b); err != nil {
+		return err
+	}
+	switch literal := r.value.String(); literal {
+	case "true":
+		r.token = BOOLEAN
+		r.value.Reset()
+		r.value.WriteByte(1)
+	case "false":
+		r.token = BOOLEAN
+		r.value.Reset()
+		r.value.WriteByte(0)
+	case "null":
+		r.token = NULL
+	case "NaN", "Infinity", "-Infinity":
 		r.token = NUMBER
-		if !skipValue {
-			if err := r.value.WriteByte(b); err != nil {
+	default:
+		if validNumber(r.value.Bytes()) {
+			r.token = NUMBER
+		} else {
+			r.token = STRING
+		}
+	}
+	return nil
+}
+
+// Return true if b is a number as specified by RFC 4627.
+func validNumber(b []byte) bool {
+	i := 0
+	if i < len(b) && b[i] == '-' {
+		i++
+	}
+	if i >= len(b) {
+		return false
+	}
+	if b[i] == '0' {
+		i++
+	} else if b[i] >= '1' && b[i] <= '9' {
+		for i++; i < len(b) && b[i] >= '0' && b[i] <= '9'; i++ {
+		}
+	} else {
+		return false
+	}
+	if i < len(b) && b[i] == '.' {
+		i++
+		if i >= len(b) || b[i] < '0' || b[i] > '9' {
+			return false
+		}
+		for i++; i < len(b) && b[i] >= '0' && b[i] <= '9'; i++ {
+		}
+	}
+	if i < len(b) && (b[i] == 'e' || b[i] == 'E') {
+		i++
+		if i < len(b) && (b[i] == '+' || b[i] == '-') {
+			i++
+		}
+		if i >= len(b) || b[i] < '0' || b[i] > '9' {
+			return false
+		}
+		for i++; i < len(b) && b[i] >= '0' && b[i] <= '9'; i++ {
+		}
+	}
+	return i == len(b)
+}
+
+// Read the four hexadecimal digits of a \u escape sequence.
+func (r *Reader) readHex4() (uint64, error) {
+	var codeUnit uint64
+	for i := 0; i < 4; i++ {
+		b, err := r.readByte()
+		if err != nil {
+			if err == io.EOF {
+				return 0, r.unexpectedEOF("hexadecimal digit")
+			}
+			return 0, err
+		}
+		switch {
+		case b >= '0' && b <= '9':
+			codeUnit = codeUnit<<4 | uint64(b-'0')
+		case b >= 'a' && b <= 'f':
+			codeUnit = codeUnit<<4 | uint64(b-'a'+10)
+		case b >= 'A' && b <= 'F':
+			codeUnit = codeUnit<<4 | uint64(b-'A'+10)
+		default:
+			return 0, r.unexpected("hexadecimal digit", b)
+		}
+	}
+	return codeUnit, nil
+}
+
+// Read the rest of a UTF-8 sequence given its first byte, and handle it
+// according to the UTF-8 policy if it is invalid.
+func (r *Reader) readUTF8(skipValue bool, b byte) error {
+	var buf [utf8.UTFMax]byte
+	buf[0] = b
+	n, length := 1, 1
+	switch {
+	case b >= 0xc2 && b < 0xe0:
+		length = 2
+	case b >= 0xe0 && b < 0xf0:
+		length = 3
+	case b >= 0xf0 && b < 0xf5:
+		length = 4
+	}
+	for n < length {
+		b, err := r.readByte()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if b < 0x80 || b >= 0xc0 {
+			if err := r.unreadByte(); err != nil {
 				return err
 			}
+			break
+		}
+		buf[n] = b
+		n++
+	}
+	if n < length || !utf8.Valid(buf[:n]) {
+		switch r.policy {
+		case RejectInvalidUTF8:
+			return r.unexpected("UTF-8", buf[:n]...)
+		case ReplaceInvalidUTF8:
+			n = utf8.EncodeRune(buf[:], utf8.RuneError)
+		}
+	}
+	if !skipValue {
+		if _, err := r.value.Write(buf[:n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set the text of the current token to buf[start:end].
+func (r *Reader) setSub(skipValue bool, start, end int) {
+	if !skipValue {
+		r.subStart, r.subEnd = start, end
+		r.hasSub = true
+	}
+}
+
+// Return the text of the current token.
+func (r *Reader) valueBytes() []byte {
+	if r.hasSub {
+		return r.sub
+	}
+	return r.value.Bytes()
+}
+
+// The bytes that end a run of plain ASCII in a string.
+var stringStops = func() (stops [256]bool) {
+	for b := range stops {
+		stops[b] = b < 0x20 || b >= 0x80 || b == '"' || b == '\'' || b == '\\'
+	}
+	return stops
+}()
+
+// Consume the string or name at r.pos, refilling the window as needed,
+// and set it as the text of the current token, if it needs no unescaping,
+// is valid UTF-8 and is within the limits.  Otherwise, return false
+// without consuming anything, so that it is read byte by byte.
+func (r *Reader) scanString(skipValue bool, quote byte) (bool, error) {
+	ascii := true
+	i := r.pos
+	for {
+		for ; i < len(r.buf); i++ {
+			for i < len(r.buf) && !stringStops[r.buf[i]] {
+				i++
+			}
+			if i >= len(r.buf) {
+				break
+			}
+			switch b := r.buf[i]; {
+			case b == quote:
+				if !ascii && r.policy != PassInvalidUTF8 && !utf8.Valid(r.buf[r.pos:i]) {
+					return false, nil
+				} else if max := r.limits.MaxStringBytes; max > 0 && i-r.pos > max {
+					return false, nil
+				}
+				r.setSub(skipValue, r.pos, i)
+				r.offset += int64(i + 1 - r.pos)
+				r.pos = i + 1
+				return true, nil
+			case b == '\\' || b < 0x20:
+				return false, nil
+			case b >= 0x80:
+				ascii = false
+			}
 		}
-		return r.readNumber(skipValue, false, true)
-	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
-		r.token = NUMBER
-		if !skipValue {
-			if err := r.value.WriteByte(b); err != nil {
-				return err
+		if max := r.limits.MaxStringBytes; max > 0 && i-r.pos > max {
+			return false, nil
+		}
+		n := i - r.pos
+		if err := r.more(); err != nil {
+			if _, ok := err.(*SizeLimitError); ok || err == io.EOF {
+				return false, nil
 			}
+			return false, err
 		}
-		return r.readNumber(skipValue, false, false)
-	default:
-		return InvalidInput
+		i = r.pos + n
 	}
 }
 
-func (r *Reader) readStringOrName(skipValue bool) error {
+func (r *Reader) readStringOrName(skipValue bool, quote byte) error {
+	if ok, err := r.scanString(skipValue, quote); err != nil {
+		return err
+	} else if ok {
+		return r.readValueEnd("end of string")
+	}
+	start := r.offset
 loop:
 	for {
-		b, err := r.r.ReadByte()
+		max := r.limits.MaxStringBytes
+		if max > 0 && r.offset-start > int64(max) {
+			return &StringLimitError{Offset: start - 1, Limit: max}
+		}
+		// Copy a run of bytes that need no checking.
+		end := len(r.buf)
+		if max > 0 && int64(end-r.pos) > int64(max)+1-(r.offset-start) {
+			end = r.pos + int(int64(max)+1-(r.offset-start))
+		}
+		i := r.pos
+		for i < end && r.buf[i] >= 0x20 && r.buf[i] != quote && r.buf[i] != '\\' && (r.buf[i] < 0x80 || r.policy == PassInvalidUTF8) {
+			i++
+		}
+		if i > r.pos {
+			if !skipValue {
+				r.value.Write(r.buf[r.pos:i])
+			}
+			r.offset += int64(i - r.pos)
+			r.pos = i
+			continue
+		}
+		b, err := r.readByte()
 		if err != nil {
 			if err == io.EOF {
-				return io.ErrUnexpectedEOF
+				return r.unexpectedEOF("'" + string(quote) + "'")
 			}
 			return err
 		}
 		switch b {
-		case '"':
+		case quote:
 			break loop
 		case '\\':
-			b, err = r.r.ReadByte()
+			b, err = r.readByte()
 			if err != nil {
 				if err == io.EOF {
-					return io.ErrUnexpectedEOF
+					return r.unexpectedEOF("escape sequence")
 				}
 				return err
 			}
@@ -646,6 +2001,15 @@ loop:
 						return err
 					}
 				}
+			case '\'':
+				if !r.lenient {
+					return r.unexpected("escape sequence", '\\', b)
+				}
+				if !skipValue {
+					if err := r.value.WriteByte(b); err != nil {
+						return err
+					}
+				}
 			case 'b':
 				if !skipValue {
 					if err := r.value.WriteByte(8); err != nil {
@@ -677,35 +2041,34 @@ loop:
 					}
 				}
 			case 'u':
-				var buf [4]byte
-				if _, err := r.r.Read(buf[:]); err != nil {
-					return err
-				}
-				codePoint, err := strconv.ParseUint(string(buf[:]), 16, 16)
+				codePoint, err := r.readHex4()
 				if err != nil {
 					return err
 				}
 				if codePoint >= 0xdc00 && codePoint < 0xe000 {
-					return InvalidInput
+					return r.syntaxError(r.offset-6, []byte(strconv.QuoteRuneToASCII(rune(codePoint))), "high surrogate", InvalidInput)
 				} else if codePoint >= 0xd800 && codePoint < 0xdc00 {
-					if b, err := r.r.ReadByte(); err != nil {
+					if b, err := r.readByte(); err != nil {
+						if err == io.EOF {
+							return r.unexpectedEOF("low surrogate")
+						}
 						return err
 					} else if b != '\\' {
-						return InvalidInput
+						return r.unexpected("low surrogate", b)
 					}
-					if b, err := r.r.ReadByte(); err != nil {
+					if b, err := r.readByte(); err != nil {
+						if err == io.EOF {
+							return r.unexpectedEOF("low surrogate")
+						}
 						return err
 					} else if b != 'u' {
-						return InvalidInput
+						return r.unexpected("low surrogate", '\\', b)
 					}
-					if _, err := r.r.Read(buf[:]); err != nil {
-						return err
-					}
-					lowSurrogate, err := strconv.ParseUint(string(buf[:]), 16, 16)
+					lowSurrogate, err := r.readHex4()
 					if err != nil {
 						return err
 					} else if lowSurrogate < 0xdc00 || lowSurrogate >= 0xe000 {
-						return InvalidInput
+						return r.syntaxError(r.offset-6, []byte(strconv.QuoteRuneToASCII(rune(lowSurrogate))), "low surrogate", InvalidInput)
 					}
 					codePoint = 0x10000 + ((codePoint & 0x3ff) << 10) + (lowSurrogate & 0x3ff)
 				}
@@ -714,58 +2077,54 @@ loop:
 						return err
 					}
 				}
+			default:
+				return r.unexpected("escape sequence", '\\', b)
 			}
 		default:
 			if b < 0x20 {
-				return InvalidInput
-			}
-			if !skipValue {
+				return r.unexpected("string character", b)
+			} else if b >= 0x80 && r.policy != PassInvalidUTF8 {
+				if err := r.readUTF8(skipValue, b); err != nil {
+					return err
+				}
+			} else if !skipValue {
 				if err := r.value.WriteByte(b); err != nil {
 					return err
 				}
 			}
 		}
 	}
-	for {
-		b, err := r.r.ReadByte()
-		if err != nil {
-			if err == io.EOF {
-				r.token = STRING
-				r.hasNext = false
-				return nil
-			}
-			return err
-		}
-		switch b {
-		case 0x20, 0x09, 0x0a, 0x0d:
-		case ',':
-			r.token = STRING
-			r.hasNext = true
-			return nil
-		case ':':
-			r.token = NAME
-			r.hasNext = false
-			return nil
-		case ']', '}':
-			r.token = STRING
-			r.hasNext = false
-			return r.r.UnreadByte()
-		default:
-			return InvalidInput
-		}
-	}
+	return r.readValueEnd("end of string")
 }
 
-func (r *Reader) readNumber(skipValue, digitNeeded, leadingZero bool) error {
+func (r *Reader) readNumber(digitNeeded, leadingZero bool) error {
+	start := r.offset - 1
 	intDone := false
 	fracDone := false
 	signPossible := false
 	for {
-		b, err := r.r.ReadByte()
+		if !digitNeeded && !leadingZero || intDone {
+			// Consume a run of digits that need no checking.
+			i := r.pos
+			for i < len(r.buf) && r.buf[i] >= '0' && r.buf[i] <= '9' {
+				i++
+			}
+			if i > r.pos {
+				r.offset += int64(i - r.pos)
+				r.pos = i
+				digitNeeded = false
+				signPossible = false
+				if max := r.limits.MaxNumberBytes; max > 0 && r.offset-start > int64(max) {
+					return &NumberLimitError{Offset: start, Limit: max}
+				}
+				continue
+			}
+		}
+		b, err := r.readByte()
 		if err != nil {
 			if err == io.EOF {
 				if digitNeeded {
-					return io.ErrUnexpectedEOF
+					return r.unexpectedEOF("digit")
 				} else {
 					return nil
 				}
@@ -778,23 +2137,30 @@ func (r *Reader) readNumber(skipValue, digitNeeded, leadingZero bool) error {
 				leadingZero = true
 				digitNeeded = false
 			} else if leadingZero && !intDone {
-				return InvalidInput
+				return r.unexpected("'.', 'e' or end of number", b)
 			} else {
 				leadingZero = false
 			}
 		case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			if leadingZero && !intDone {
+				return r.unexpected("'.', 'e' or end of number", b)
+			}
 			leadingZero = false
 			digitNeeded = false
 			signPossible = false
 		case '.':
-			if intDone {
-				return InvalidInput
+			if digitNeeded {
+				return r.unexpected("digit", b)
+			} else if intDone {
+				return r.unexpected("digit, 'e' or end of number", b)
 			}
 			intDone = true
 			digitNeeded = true
 		case 'e', 'E':
-			if fracDone {
-				return InvalidInput
+			if digitNeeded {
+				return r.unexpected("digit", b)
+			} else if fracDone {
+				return r.unexpected("digit or end of number", b)
 			}
 			intDone = true
 			fracDone = true
@@ -802,22 +2168,21 @@ func (r *Reader) readNumber(skipValue, digitNeeded, leadingZero bool) error {
 			signPossible = true
 		case '+', '-':
 			if !signPossible {
-				return InvalidInput
+				return r.unexpected("digit", b)
 			}
 			signPossible = false
 		default:
 			if digitNeeded {
-				return InvalidInput
+				return r.unexpected("digit", b)
+			} else if b == recordSeparator && r.mode == JSONSequence {
+				return r.unexpected("whitespace after possibly truncated value", b)
+			} else if isLiteral(b) && !r.isDelimiter(b) {
+				return r.unexpected("digit or end of number", b)
 			}
-			if err := r.r.UnreadByte(); err != nil {
-				return err
-			}
-			return r.readTokenEnd()
+			return r.unreadByte()
 		}
-		if !skipValue {
-			if err := r.value.WriteByte(b); err != nil {
-				return err
-			}
+		if max := r.limits.MaxNumberBytes; max > 0 && r.offset-start > int64(max) {
+			return &NumberLimitError{Offset: start, Limit: max}
 		}
 	}
 }
@@ -826,12 +2191,13 @@ func (r *Reader) readNumber(skipValue, digitNeeded, leadingZero bool) error {
 // beginning of a new array.
 func (r *Reader) BeginArray() error {
 	if r.token == NO_TOKEN {
-		if err := r.readToken(false); err != nil {
+		if err := r.nextToken(false); err != nil {
 			return err
 		}
 	}
 	if r.token == BEGIN_ARRAY {
 		r.token = NO_TOKEN
+		r.pushPath(false)
 		return nil
 	}
 	return IllegalState
@@ -841,12 +2207,13 @@ func (r *Reader) BeginArray() error {
 // beginning of a new object.
 func (r *Reader) BeginObject() error {
 	if r.token == NO_TOKEN {
-		if err := r.readToken(false); err != nil {
+		if err := r.nextToken(false); err != nil {
 			return err
 		}
 	}
 	if r.token == BEGIN_OBJECT {
 		r.token = NO_TOKEN
+		r.pushPath(true)
 		return nil
 	}
 	return IllegalState
@@ -856,12 +2223,13 @@ func (r *Reader) BeginObject() error {
 // end of the current array.
 func (r *Reader) EndArray() error {
 	if r.token == NO_TOKEN {
-		if err := r.readToken(false); err != nil {
+		if err := r.nextToken(false); err != nil {
 			return err
 		}
 	}
 	if r.token == END_ARRAY {
 		r.token = NO_TOKEN
+		r.popPath()
 		return nil
 	}
 	return IllegalState
@@ -871,12 +2239,13 @@ func (r *Reader) EndArray() error {
 // end of the current object.
 func (r *Reader) EndObject() error {
 	if r.token == NO_TOKEN {
-		if err := r.readToken(false); err != nil {
+		if err := r.nextToken(false); err != nil {
 			return err
 		}
 	}
 	if r.token == END_OBJECT {
 		r.token = NO_TOKEN
+		r.popPath()
 		return nil
 	}
 	return IllegalState
@@ -884,23 +2253,24 @@ func (r *Reader) EndObject() error {
 
 // Return true if the current array or object has another element.
 func (r *Reader) HasNext() (bool, error) {
-	return r.hasNext, nil
+	token, err := r.Peek()
+	if err != nil {
+		return false, err
+	}
+	return token != END_ARRAY && token != END_OBJECT && token != END_DOCUMENT, nil
 }
 
 // Return the boolean value of the next token, consuming it.
 func (r *Reader) NextBoolean() (bool, error) {
 	if r.token == NO_TOKEN {
-		if err := r.readToken(false); err != nil {
+		if err := r.nextToken(false); err != nil {
 			return false, err
 		}
 	}
 	if r.token == BOOLEAN {
 		r.token = NO_TOKEN
-		if b, err := r.value.ReadByte(); err != nil {
-			panic("rgo: Internal error")
-		} else {
-			return b != 0, nil
-		}
+		r.endValue()
+		return r.valueBytes()[0] != 0, nil
 	}
 	return false, IllegalState
 }
@@ -920,14 +2290,15 @@ func (r *Reader) NextFloat64() (float64, error) {
 
 func (r *Reader) nextFloat(bitSize int) (float64, error) {
 	if r.token == NO_TOKEN {
-		if err := r.readToken(false); err != nil {
+		if err := r.nextToken(false); err != nil {
 			return 0, err
 		}
 	}
 	switch r.token {
 	case STRING, NUMBER:
 		r.token = NO_TOKEN
-		return strconv.ParseFloat(r.value.String(), bitSize)
+		r.endValue()
+		return strconv.ParseFloat(string(r.valueBytes()), bitSize)
 	default:
 		return 0, IllegalState
 	}
@@ -936,51 +2307,198 @@ func (r *Reader) nextFloat(bitSize int) (float64, error) {
 // Return the int value of the next token, consuming it.  If the next
 // token is a string, this method will attempt to parse it as a int.
 func (r *Reader) NextInt() (int, error) {
-	value, err := r.NextInt64()
+	value, err := r.nextInt(strconv.IntSize, "int")
 	return int(value), err
 }
 
+// Return the int8 value of the next token, consuming it.  If the next
+// token is a string, this method will attempt to parse it as a int8.
+func (r *Reader) NextInt8() (int8, error) {
+	value, err := r.nextInt(8, "int8")
+	return int8(value), err
+}
+
+// Return the int16 value of the next token, consuming it.  If the next
+// token is a string, this method will attempt to parse it as a int16.
+func (r *Reader) NextInt16() (int16, error) {
+	value, err := r.nextInt(16, "int16")
+	return int16(value), err
+}
+
+// Return the int32 value of the next token, consuming it.  If the next
+// token is a string, this method will attempt to parse it as a int32.
+func (r *Reader) NextInt32() (int32, error) {
+	value, err := r.nextInt(32, "int32")
+	return int32(value), err
+}
+
 // Return the int64 value of the next token, consuming it.  If the next
 // token is a string, this method will attempt to parse it as a int64.
 func (r *Reader) NextInt64() (int64, error) {
+	return r.nextInt(64, "int64")
+}
+
+func (r *Reader) nextInt(bitSize int, typeName string) (int64, error) {
+	if r.token == NO_TOKEN {
+		if err := r.nextToken(false); err != nil {
+			return 0, err
+		}
+	}
+	switch r.token {
+	case STRING, NUMBER:
+		r.token = NO_TOKEN
+		value, err := strconv.ParseInt(string(r.valueBytes()), 10, bitSize)
+		if errors.Is(err, strconv.ErrRange) {
+			value, err = 0, r.rangeError(typeName)
+		}
+		r.endValue()
+		return value, err
+	default:
+		return 0, IllegalState
+	}
+}
+
+// Return the uint value of the next token, consuming it.  If the next
+// token is a string, this method will attempt to parse it as a uint.
+func (r *Reader) NextUint() (uint, error) {
+	value, err := r.nextUint(strconv.IntSize, "uint")
+	return uint(value), err
+}
+
+// Return the uint8 value of the next token, consuming it.  If the next
+// token is a string, this method will attempt to parse it as a uint8.
+func (r *Reader) NextUint8() (uint8, error) {
+	value, err := r.nextUint(8, "uint8")
+	return uint8(value), err
+}
+
+// Return the uint16 value of the next token, consuming it.  If the next
+// token is a string, this method will attempt to parse it as a uint16.
+func (r *Reader) NextUint16() (uint16, error) {
+	value, err := r.nextUint(16, "uint16")
+	return uint16(value), err
+}
+
+// Return the uint32 value of the next token, consuming it.  If the next
+// token is a string, this method will attempt to parse it as a uint32.
+func (r *Reader) NextUint32() (uint32, error) {
+	value, err := r.nextUint(32, "uint32")
+	return uint32(value), err
+}
+
+// Return the uint64 value of the next token, consuming it.  If the next
+// token is a string, this method will attempt to parse it as a uint64.
+func (r *Reader) NextUint64() (uint64, error) {
+	return r.nextUint(64, "uint64")
+}
+
+func (r *Reader) nextUint(bitSize int, typeName string) (uint64, error) {
 	if r.token == NO_TOKEN {
-		if err := r.readToken(false); err != nil {
+		if err := r.nextToken(false); err != nil {
 			return 0, err
 		}
 	}
 	switch r.token {
 	case STRING, NUMBER:
 		r.token = NO_TOKEN
-		return strconv.ParseInt(r.value.String(), 10, 64)
+		s := string(r.valueBytes())
+		value, err := strconv.ParseUint(s, 10, bitSize)
+		if errors.Is(err, strconv.ErrRange) {
+			value, err = 0, r.rangeError(typeName)
+		} else if err != nil && len(s) > 1 && s[0] == '-' {
+			// Negative integers are out of range, but -0 is zero.
+			if value, err = strconv.ParseUint(s[1:], 10, bitSize); (err == nil && value != 0) || errors.Is(err, strconv.ErrRange) {
+				value, err = 0, r.rangeError(typeName)
+			} else if err != nil {
+				err = &strconv.NumError{Func: "ParseUint", Num: s, Err: strconv.ErrSyntax}
+			}
+		}
+		r.endValue()
+		return value, err
 	default:
 		return 0, IllegalState
 	}
 }
 
+// Return a RangeError for the current token, which is a number that is
+// out of range for typeName.
+func (r *Reader) rangeError(typeName string) error {
+	return &RangeError{Path: r.Path(), Number: string(r.valueBytes()), Type: typeName}
+}
+
+// Return the next token as a Number, consuming it.  If the next token is
+// a string, this method will attempt to parse it as a Number.
+func (r *Reader) NextNumber() (Number, error) {
+	if r.token == NO_TOKEN {
+		if err := r.nextToken(false); err != nil {
+			return "", err
+		}
+	}
+	switch r.token {
+	case STRING, NUMBER:
+		token := r.token
+		r.token = NO_TOKEN
+		r.endValue()
+		if token == STRING && !validNumber(r.valueBytes()) {
+			return "", &strconv.NumError{Func: "NextNumber", Num: string(r.valueBytes()), Err: strconv.ErrSyntax}
+		}
+		return Number(string(r.valueBytes())), nil
+	default:
+		return "", IllegalState
+	}
+}
+
 // Return the next token, a property name, consuming it.
 func (r *Reader) NextName() (string, error) {
 	if r.token == NO_TOKEN {
-		if err := r.readToken(false); err != nil {
+		if err := r.nextToken(false); err != nil {
 			return "", err
 		}
 	}
 	if r.token == NAME {
 		r.token = NO_TOKEN
-		return r.value.String(), nil
+		name := string(r.valueBytes())
+		if len(r.path) > 0 {
+			r.path[len(r.path)-1].name = name
+			r.path[len(r.path)-1].nameBytes = nil
+		}
+		return name, nil
 	}
 	return "", IllegalState
 }
 
+// Return the next token, a property name, consuming it.  If the Reader
+// was created by NewBytesReader and the name needs no unescaping, the
+// result is a sub-slice of the input.  Otherwise, it is a copy.
+func (r *Reader) NextNameBytes() ([]byte, error) {
+	if r.token == NO_TOKEN {
+		if err := r.nextToken(false); err != nil {
+			return nil, err
+		}
+	}
+	if r.token == NAME {
+		r.token = NO_TOKEN
+		name := r.bytesValue()
+		if len(r.path) > 0 {
+			r.path[len(r.path)-1].name = ""
+			r.path[len(r.path)-1].nameBytes = name
+		}
+		return name, nil
+	}
+	return nil, IllegalState
+}
+
 // Consume the next token from the JSON stream and assert that it is a
 // literal null.
 func (r *Reader) NextNull() error {
 	if r.token == NO_TOKEN {
-		if err := r.readToken(false); err != nil {
+		if err := r.nextToken(false); err != nil {
 			return err
 		}
 	}
 	if r.token == NULL {
 		r.token = NO_TOKEN
+		r.endValue()
 		return nil
 	}
 	return IllegalState
@@ -990,23 +2508,281 @@ func (r *Reader) NextNull() error {
 // token is a number, this method will return its string form.
 func (r *Reader) NextString() (string, error) {
 	if r.token == NO_TOKEN {
-		if err := r.readToken(false); err != nil {
+		if err := r.nextToken(false); err != nil {
 			return "", err
 		}
 	}
 	switch r.token {
 	case STRING, NUMBER:
 		r.token = NO_TOKEN
-		return r.value.String(), nil
+		r.endValue()
+		return string(r.valueBytes()), nil
+	default:
+		return "", IllegalState
+	}
+}
+
+// Return the string value of the next token, consuming it.  If the next
+// token is a number, this method will return its text.  If the Reader was
+// created by NewBytesReader and the value needs no unescaping, the result
+// is a sub-slice of the input.  Otherwise, it is a copy.
+func (r *Reader) NextStringBytes() ([]byte, error) {
+	if r.token == NO_TOKEN {
+		if err := r.nextToken(false); err != nil {
+			return nil, err
+		}
+	}
+	switch r.token {
+	case STRING, NUMBER:
+		r.token = NO_TOKEN
+		r.endValue()
+		return r.bytesValue(), nil
+	default:
+		return nil, IllegalState
+	}
+}
+
+// Return the text of the current token as a sub-slice of the input if
+// possible, or as a copy.
+func (r *Reader) bytesValue() []byte {
+	if r.hasSub && r.src == nil {
+		return r.sub
+	}
+	return r.copyBytes(r.valueBytes())
+}
+
+// The size of the blocks from which copies of short values are taken.
+const copyBlockSize = 4096
+
+// Return a copy of b.  Short copies share a block, so that copying many
+// names and strings needs few allocations.
+func (r *Reader) copyBytes(b []byte) []byte {
+	if len(b) > cap(r.block)-len(r.block) {
+		if len(b) > copyBlockSize/16 {
+			return append([]byte{}, b...)
+		}
+		r.block = make([]byte, 0, copyBlockSize)
+	}
+	start := len(r.block)
+	r.block = append(r.block, b...)
+	return r.block[start:len(r.block):len(r.block)]
+}
+
+// Move to the next document, returning false if there are no more.  This
+// must be called before the first document, and after the END_DOCUMENT
+// token that follows each document when the DocumentMode is
+// MultipleDocuments or JSONLines.  In the middle of a document, it returns
+// IllegalState.
+func (r *Reader) NextDocument() (bool, error) {
+	if r.mode == JSONSequence {
+		return r.nextRecord()
+	}
+	token, err := r.Peek()
+	if err != nil {
+		return false, err
+	}
+	if token == END_DOCUMENT && r.stack[0] == closedDocument && r.mode != ValueStream {
+		r.token = NO_TOKEN
+		r.stack[0] = emptyDocument
+		if token, err = r.Peek(); err != nil {
+			return false, err
+		}
+	}
+	switch token {
+	case END_DOCUMENT:
+		return false, nil
+	case END_ARRAY, END_OBJECT, NAME:
+		return false, IllegalState
+	}
+	if len(r.path) > 0 {
+		return false, IllegalState
+	}
+	return true, nil
+}
+
+// Skip the rest of the current record of a JSON text sequence, and start
+// reading the next.
+func (r *Reader) nextRecord() (bool, error) {
+	r.mark = -1
+	if len(r.stack) > 1 || r.stack[0] != emptyDocument {
+		if err := r.skipRecord(); err != nil {
+			return false, err
+		}
+	}
+	r.resetDocument()
+	token, err := r.Peek()
+	if err != nil {
+		return false, err
+	}
+	return token != END_DOCUMENT, nil
+}
+
+// Discard the rest of the current record, so that reading can continue
+// with the next record after an error, such as InvalidInput.  The record
+// ends with the delimiter set by SetRecordDelimiter, which is also
+// discarded, except for the RS that starts the next record of a JSON text
+// sequence.  The Reader is then ready to read the next document.  Return
+// the offsets of the start and end of the discarded record, which starts
+// at the first byte of its top-level value, or at the end of the previous
+// record if the error came before that value, and does not include the
+// delimiter.
+func (r *Reader) Recover() (start, end int64, err error) {
+	start, end = r.recordStart, r.offset
+	r.mark = -1
+	if len(r.stack) > 1 || r.stack[0] == nonemptyDocument {
+		delimiter := r.recordDelimiter()
+		for {
+			b, err := r.readByte()
+			if err != nil {
+				if err != io.EOF {
+					return start, r.offset, err
+				}
+				end = r.offset
+				break
+			}
+			if b == delimiter {
+				end = r.offset - 1
+				if delimiter == recordSeparator {
+					if err := r.unreadByte(); err != nil {
+						return start, end, err
+					}
+				}
+				break
+			}
+		}
+	}
+	r.resetDocument()
+	r.recordStart = r.offset
+	return start, end, nil
+}
+
+// Reset the state of the Reader to the start of a document.
+func (r *Reader) resetDocument() {
+	r.token = NO_TOKEN
+	r.lookahead = r.lookahead[:0]
+	r.stack = append(r.stack[:0], emptyDocument)
+	r.path = r.path[:0]
+	r.value.Reset()
+	r.hasSub = false
+	r.capturing = false
+	r.failed = nil
+}
+
+// The maximum number of tokens that PeekN can return.
+const MaxLookahead = 64
+
+// A token and its value, as returned by PeekN.
+type PeekedToken struct {
+	Token Token
+	// The text of a NAME, STRING or NUMBER, "true" or "false" for a
+	// BOOLEAN, and empty otherwise.
+	Value string
+}
+
+// Return the next n tokens and their values without consuming them.  Fewer
+// than n are returned if the END_DOCUMENT token is reached.  The tokens
+// are consumed by later calls to the other methods as usual.  Returns
+// IllegalArgument if n is less than 1 or greater than MaxLookahead.
+func (r *Reader) PeekN(n int) ([]PeekedToken, error) {
+	if n < 1 || n > MaxLookahead {
+		return nil, IllegalArgument
+	}
+	if _, err := r.Peek(); err != nil {
+		return nil, err
+	}
+	if len(r.lookahead)+1 < n && r.token != END_DOCUMENT && (len(r.lookahead) == 0 || r.lookahead[len(r.lookahead)-1].token != END_DOCUMENT) {
+		// Put the current token at the front of the lookahead buffer
+		// while reading ahead.
+		r.lookahead = append(r.lookahead, lookaheadToken{})
+		copy(r.lookahead[1:], r.lookahead)
+		r.lookahead[0] = r.currentToken()
+		r.token = NO_TOKEN
+		for len(r.lookahead) < n && r.lookahead[len(r.lookahead)-1].token != END_DOCUMENT {
+			if err := r.readToken(false); err != nil {
+				// Restore the current token from the front of the
+				// buffer, which cannot fail.
+				r.nextToken(false)
+				return nil, err
+			}
+			r.lookahead = append(r.lookahead, r.currentToken())
+			r.token = NO_TOKEN
+		}
+		r.nextToken(false)
+	}
+	tokens := []PeekedToken{r.peekedToken(r.token, r.valueBytes())}
+	for i := 0; i < len(r.lookahead) && len(tokens) < n; i++ {
+		tokens = append(tokens, r.peekedToken(r.lookahead[i].token, r.lookahead[i].value))
+	}
+	return tokens, nil
+}
+
+func (r *Reader) peekedToken(token Token, value []byte) PeekedToken {
+	switch token {
+	case NAME, STRING, NUMBER:
+		return PeekedToken{Token: token, Value: string(value)}
+	case BOOLEAN:
+		return PeekedToken{Token: token, Value: strconv.FormatBool(value[0] != 0)}
 	default:
+		return PeekedToken{Token: token}
+	}
+}
+
+// Return the current token, with a copy of its value unless it is a
+// sub-slice of an input byte slice.
+func (r *Reader) currentToken() lookaheadToken {
+	t := lookaheadToken{token: r.token, start: r.rawStart, end: r.rawEnd}
+	if r.hasSub && r.src == nil {
+		t.value = r.sub
+	} else {
+		t.value = append([]byte(nil), r.valueBytes()...)
+	}
+	return t
+}
+
+// Make the next token the current token, taking it from the lookahead
+// buffer if it is not empty.
+func (r *Reader) nextToken(skipValue bool) error {
+	if r.failed != nil {
+		return r.failed
+	}
+	if len(r.lookahead) == 0 {
+		return r.readToken(skipValue)
+	}
+	t := r.lookahead[0]
+	r.lookahead = r.lookahead[:copy(r.lookahead, r.lookahead[1:])]
+	r.token = t.token
+	r.sub = t.value
+	r.hasSub = true
+	r.rawStart = t.start
+	r.rawEnd = t.end
+	return nil
+}
+
+// Return the next token, a property name, without consuming it.
+func (r *Reader) PeekName() (string, error) {
+	if token, err := r.Peek(); err != nil {
+		return "", err
+	} else if token != NAME {
+		return "", IllegalState
+	}
+	return string(r.valueBytes()), nil
+}
+
+// Return the string value of the next token without consuming it.  If the
+// next token is a number, this method will return its string form.
+func (r *Reader) PeekString() (string, error) {
+	if token, err := r.Peek(); err != nil {
+		return "", err
+	} else if token != STRING && token != NUMBER {
 		return "", IllegalState
 	}
+	return string(r.valueBytes()), nil
 }
 
 // Return the type of the next token without consuming it.
 func (r *Reader) Peek() (Token, error) {
 	if r.token == NO_TOKEN {
-		if err := r.readToken(false); err != nil {
+		if err := r.nextToken(false); err != nil {
 			return r.token, err
 		}
 	}
@@ -1016,37 +2792,185 @@ func (r *Reader) Peek() (Token, error) {
 	return r.token, nil
 }
 
+// Return the exact bytes of the next value, consuming it.  If it is an
+// object or array, all nested elements are included.  The value is
+// validated as it is read, as with SkipValue.
+func (r *Reader) NextRawValue() ([]byte, error) {
+	if r.token == NO_TOKEN {
+		if err := r.nextToken(true); err != nil {
+			return nil, err
+		}
+	}
+	switch r.token {
+	case NAME, END_ARRAY, END_OBJECT, END_DOCUMENT:
+		return nil, IllegalState
+	}
+	r.captureStart = r.rawStart
+	r.capturing = true
+	err := r.SkipValue()
+	r.capturing = false
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), r.buf[r.captureStart:r.rawEnd]...), nil
+}
+
 // Skip the next value recursively.  If it is an object or array, all
 // nested elements are skipped.  This method is intended for use when
-// the JSON token stream contains unrecognized or unhandled values.
+// the JSON token stream contains unrecognized or unhandled values.  See
+// SkipValueUnchecked for a faster alternative that does not validate.
 func (r *Reader) SkipValue() error {
 	if r.token == NO_TOKEN {
-		if err := r.readToken(true); err != nil {
+		if err := r.nextToken(true); err != nil {
 			return err
 		}
 	}
-	if r.token == NAME {
+	switch r.token {
+	case NAME:
 		return IllegalState
+	case END_ARRAY, END_OBJECT:
+		return InvalidInput
 	}
-	for nesting := 0; ; {
+	depth := 0
+	for {
 		switch r.token {
-		case NO_TOKEN:
-			panic("rgo: Internal error")
 		case BEGIN_ARRAY, BEGIN_OBJECT:
-			nesting++
+			depth++
 		case END_ARRAY, END_OBJECT:
-			if nesting <= 0 {
-				return InvalidInput
-			}
-			nesting--
-		default:
+			depth--
 		}
 		r.token = NO_TOKEN
-		if nesting <= 0 {
+		if depth == 0 {
+			r.endValue()
 			return nil
 		}
-		if err := r.readToken(true); err != nil {
+		if err := r.nextToken(true); err != nil {
+			return err
+		}
+	}
+}
+
+// Skip the next value as SkipValue does, but without validating the
+// contents of an object or array, which are only scanned for brackets and
+// the ends of strings.  This is much faster for large values, but
+// malformed input within them is not detected, and only MaxDepth and
+// MaxTotalBytes of the Limits are enforced within them.  When lenient, or
+// when tokens have been read ahead by PeekN, this is the same as
+// SkipValue.
+func (r *Reader) SkipValueUnchecked() error {
+	if r.lenient || len(r.lookahead) > 0 {
+		return r.SkipValue()
+	}
+	if r.token == NO_TOKEN {
+		if err := r.nextToken(true); err != nil {
+			return err
+		}
+	}
+	switch r.token {
+	case NAME:
+		return IllegalState
+	case END_ARRAY, END_OBJECT:
+		return InvalidInput
+	case BEGIN_ARRAY, BEGIN_OBJECT:
+		if err := r.skipContainer(); err != nil {
+			return r.fail(err)
+		}
+	}
+	r.token = NO_TOKEN
+	r.endValue()
+	return nil
+}
+
+// The bytes that skipContainer stops at outside of strings.
+var skipStops = [256]bool{
+	'"': true, '[': true, ']': true, '{': true, '}': true,
+	'\n': true, recordSeparator: true,
+}
+
+// Skip the rest of the object or array that has just been started,
+// scanning only for brackets and the ends of strings.
+func (r *Reader) skipContainer() error {
+	// Nothing needs to be kept in the window.
+	r.mark = -1
+	depth := 1
+	inString, escape := false, false
+	for {
+		i := r.pos
+		for i < len(r.buf) {
+			if escape {
+				escape = false
+				i++
+				continue
+			}
+			if inString {
+				j := bytes.IndexByte(r.buf[i:], '"')
+				if j < 0 {
+					j = len(r.buf) - i
+				}
+				if k := bytes.IndexByte(r.buf[i:i+j], '\\'); k >= 0 {
+					i += k + 1
+					escape = true
+				} else if i += j; i < len(r.buf) {
+					i++
+					inString = false
+				}
+				continue
+			}
+			for i < len(r.buf) && !skipStops[r.buf[i]] {
+				i++
+			}
+			if i >= len(r.buf) {
+				break
+			}
+			b := r.buf[i]
+			i++
+			switch b {
+			case '"':
+				inString = true
+			case '[', '{':
+				depth++
+				if max := r.limits.MaxDepth; max > 0 && len(r.stack)+depth-2 > max {
+					r.consume(i)
+					return &DepthLimitError{Offset: r.offset - 1, Limit: max}
+				}
+			case ']', '}':
+				if depth--; depth > 0 {
+					break
+				}
+				r.consume(i)
+				top := len(r.stack) - 1
+				if (b == ']') != (r.stack[top] == emptyArray) {
+					if b == ']' {
+						return r.unexpected("'}'", b)
+					}
+					return r.unexpected("']'", b)
+				}
+				r.stack = r.stack[:top]
+				return nil
+			case '\n':
+				r.consume(i)
+				r.line++
+				r.prevLineStart = r.lineStart
+				r.lineStart = r.offset
+			case recordSeparator:
+				if r.mode == JSONSequence {
+					r.consume(i)
+					return r.unexpected("end of container", b)
+				}
+			}
+		}
+		r.consume(i)
+		if err := r.more(); err != nil {
+			if err == io.EOF {
+				return r.unexpectedEOF("end of container")
+			}
 			return err
 		}
 	}
 }
+
+// Consume the bytes of buf before i.
+func (r *Reader) consume(i int) {
+	r.offset += int64(i - r.pos)
+	r.pos = i
+}
@@ -2,9 +2,14 @@ package rgo
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"math"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestWriteArray(t *testing.T) {
@@ -391,7 +396,7 @@ func TestReadArray(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString(" ["))
-	if err := r.BeginArray(); err != io.ErrUnexpectedEOF {
+	if err := r.BeginArray(); !errors.Is(err, io.ErrUnexpectedEOF) {
 		if err == nil {
 			t.Errorf("TestReadArray:BeginArray:err=nil")
 		} else {
@@ -672,7 +677,7 @@ func TestSkipValue(t *testing.T) {
 		t.Errorf("TestSkipValue:BeginObject:err=%s", err.Error())
 		return
 	}
-	if err := r.SkipValue(); err != InvalidInput {
+	if err := r.SkipValue(); !errors.Is(err, InvalidInput) {
 		if err == nil {
 			t.Errorf("TestReadObject:SkipValue:err=nil")
 		} else {
@@ -691,7 +696,7 @@ func TestUTF16(t *testing.T) {
 		t.Errorf("TestUTF16:NextString=%s", value)
 	}
 	r = NewReader(bytes.NewBufferString(`"\uDD1E\uD834"`))
-	if _, err := r.NextString(); err != InvalidInput {
+	if _, err := r.NextString(); !errors.Is(err, InvalidInput) {
 		if err == nil {
 			t.Errorf("TestUTF16:NextString:err=nil")
 		} else {
@@ -700,7 +705,7 @@ func TestUTF16(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString(`"\uD834"`))
-	if _, err := r.NextString(); err != InvalidInput {
+	if _, err := r.NextString(); !errors.Is(err, InvalidInput) {
 		if err == nil {
 			t.Errorf("TestUTF16:NextString:err=nil")
 		} else {
@@ -709,7 +714,7 @@ func TestUTF16(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString(`"\uD834\"`))
-	if _, err := r.NextString(); err != InvalidInput {
+	if _, err := r.NextString(); !errors.Is(err, InvalidInput) {
 		if err == nil {
 			t.Errorf("TestUTF16:NextString:err=nil")
 		} else {
@@ -718,7 +723,7 @@ func TestUTF16(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString(`"\uD834\uD834"`))
-	if _, err := r.NextString(); err != InvalidInput {
+	if _, err := r.NextString(); !errors.Is(err, InvalidInput) {
 		if err == nil {
 			t.Errorf("TestUTF16:NextString:err=nil")
 		} else {
@@ -737,7 +742,7 @@ func TestUTF16(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString("\"\x08\""))
-	if _, err := r.NextString(); err != InvalidInput {
+	if _, err := r.NextString(); !errors.Is(err, InvalidInput) {
 		if err == nil {
 			t.Errorf("TestUTF16:NextString:err=nil")
 		} else {
@@ -746,7 +751,7 @@ func TestUTF16(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString(`"""`))
-	if _, err := r.NextString(); err != InvalidInput {
+	if _, err := r.NextString(); !errors.Is(err, InvalidInput) {
 		if err == nil {
 			t.Errorf("TestUTF16:NextString:err=nil")
 		} else {
@@ -771,7 +776,7 @@ func TestNull(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString("NULL"))
-	if err := r.NextNull(); err != InvalidInput {
+	if err := r.NextNull(); !errors.Is(err, InvalidInput) {
 		if err == nil {
 			t.Errorf("TestNull:NextNull:err=nil")
 		} else {
@@ -780,7 +785,7 @@ func TestNull(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString("nulL"))
-	if err := r.NextNull(); err != InvalidInput {
+	if err := r.NextNull(); !errors.Is(err, InvalidInput) {
 		if err == nil {
 			t.Errorf("TestNull:NextNull:err=nil")
 		} else {
@@ -789,7 +794,7 @@ func TestNull(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString("nullx"))
-	if err := r.NextNull(); err != InvalidInput {
+	if err := r.NextNull(); !errors.Is(err, InvalidInput) {
 		if err == nil {
 			t.Errorf("TestNull:NextNull:err=nil")
 		} else {
@@ -809,7 +814,7 @@ func TestEOF(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString("\""))
-	if _, err := r.NextString(); err != io.ErrUnexpectedEOF {
+	if _, err := r.NextString(); !errors.Is(err, io.ErrUnexpectedEOF) {
 		if err == nil {
 			t.Errorf("TestEOF:NextString:err=nil")
 		} else {
@@ -818,7 +823,7 @@ func TestEOF(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString("\"\\"))
-	if _, err := r.NextString(); err != io.ErrUnexpectedEOF {
+	if _, err := r.NextString(); !errors.Is(err, io.ErrUnexpectedEOF) {
 		if err == nil {
 			t.Errorf("TestEOF:NextString:err=nil")
 		} else {
@@ -917,7 +922,7 @@ func TestReadNumber(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString("-"))
-	if _, err := r.NextInt(); err != io.ErrUnexpectedEOF {
+	if _, err := r.NextInt(); !errors.Is(err, io.ErrUnexpectedEOF) {
 		if err == nil {
 			t.Errorf("TestReadNumber:NextInt:err=nil")
 		} else {
@@ -926,7 +931,7 @@ func TestReadNumber(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString("0.."))
-	if _, err := r.NextFloat64(); err != InvalidInput {
+	if _, err := r.NextFloat64(); !errors.Is(err, InvalidInput) {
 		if err == nil {
 			t.Errorf("TestReadNumber:NextFloat64:err=nil")
 		} else {
@@ -935,7 +940,7 @@ func TestReadNumber(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString("1ee"))
-	if _, err := r.NextFloat64(); err != InvalidInput {
+	if _, err := r.NextFloat64(); !errors.Is(err, InvalidInput) {
 		if err == nil {
 			t.Errorf("TestReadNumber:NextFloat64:err=nil")
 		} else {
@@ -944,7 +949,7 @@ func TestReadNumber(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString("1e++"))
-	if _, err := r.NextFloat64(); err != InvalidInput {
+	if _, err := r.NextFloat64(); !errors.Is(err, InvalidInput) {
 		if err == nil {
 			t.Errorf("TestReadNumber:NextFloat64:err=nil")
 		} else {
@@ -953,7 +958,7 @@ func TestReadNumber(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString("1+"))
-	if _, err := r.NextFloat64(); err != InvalidInput {
+	if _, err := r.NextFloat64(); !errors.Is(err, InvalidInput) {
 		if err == nil {
 			t.Errorf("TestReadNumber:NextFloat64:err=nil")
 		} else {
@@ -970,7 +975,7 @@ func TestReadNumber(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString("001"))
-	if _, err := r.NextInt(); err != InvalidInput {
+	if _, err := r.NextInt(); !errors.Is(err, InvalidInput) {
 		if err == nil {
 			t.Errorf("TestReadNumber:NextInt:err=nil")
 		} else {
@@ -987,7 +992,7 @@ func TestReadNumber(t *testing.T) {
 		return
 	}
 	r = NewReader(bytes.NewBufferString("-,"))
-	if _, err := r.NextInt(); err != InvalidInput {
+	if _, err := r.NextInt(); !errors.Is(err, InvalidInput) {
 		if err == nil {
 			t.Errorf("TestReadNumber:NextInt:err=nil")
 		} else {
@@ -1043,3 +1048,1437 @@ func TestReadString(t *testing.T) {
 		return
 	}
 }
+
+func TestLenient(t *testing.T) {
+	r := NewReader(bytes.NewBufferString(`{"a":1}`))
+	if r.IsLenient() {
+		t.Errorf("TestLenient:IsLenient=true")
+		return
+	}
+	r = NewReader(bytes.NewBufferString(")]}'\n" + `{
+	// comment
+	a = 'single' ; # comment
+	'b' => unquoted /* comment */ ;
+	"c": NaN,
+	d: [1,,2,],
+	e: -Infinity
+}`))
+	r.SetLenient(true)
+	if !r.IsLenient() {
+		t.Errorf("TestLenient:IsLenient=false")
+		return
+	}
+	if err := r.BeginObject(); err != nil {
+		t.Errorf("TestLenient:BeginObject:err=%s", err.Error())
+		return
+	}
+	if name, err := r.NextName(); err != nil {
+		t.Errorf("TestLenient:NextName:err=%s", err.Error())
+		return
+	} else if name != "a" {
+		t.Errorf("TestLenient:NextName:name=%s", name)
+		return
+	}
+	if value, err := r.NextString(); err != nil {
+		t.Errorf("TestLenient:NextString:err=%s", err.Error())
+		return
+	} else if value != "single" {
+		t.Errorf("TestLenient:NextString=%s", value)
+		return
+	}
+	if name, err := r.NextName(); err != nil {
+		t.Errorf("TestLenient:NextName:err=%s", err.Error())
+		return
+	} else if name != "b" {
+		t.Errorf("TestLenient:NextName:name=%s", name)
+		return
+	}
+	if value, err := r.NextString(); err != nil {
+		t.Errorf("TestLenient:NextString:err=%s", err.Error())
+		return
+	} else if value != "unquoted" {
+		t.Errorf("TestLenient:NextString=%s", value)
+		return
+	}
+	if name, err := r.NextName(); err != nil {
+		t.Errorf("TestLenient:NextName:err=%s", err.Error())
+		return
+	} else if name != "c" {
+		t.Errorf("TestLenient:NextName:name=%s", name)
+		return
+	}
+	if token, err := r.Peek(); err != nil {
+		t.Errorf("TestLenient:Peek:err=%s", err.Error())
+		return
+	} else if token != NUMBER {
+		t.Errorf("TestLenient:Peek:token=%d", token)
+		return
+	}
+	if value, err := r.NextFloat64(); err != nil {
+		t.Errorf("TestLenient:NextFloat64:err=%s", err.Error())
+		return
+	} else if !math.IsNaN(value) {
+		t.Errorf("TestLenient:NextFloat64=%g", value)
+		return
+	}
+	if name, err := r.NextName(); err != nil {
+		t.Errorf("TestLenient:NextName:err=%s", err.Error())
+		return
+	} else if name != "d" {
+		t.Errorf("TestLenient:NextName:name=%s", name)
+		return
+	}
+	if err := r.BeginArray(); err != nil {
+		t.Errorf("TestLenient:BeginArray:err=%s", err.Error())
+		return
+	}
+	if value, err := r.NextInt(); err != nil {
+		t.Errorf("TestLenient:NextInt:err=%s", err.Error())
+		return
+	} else if value != 1 {
+		t.Errorf("TestLenient:NextInt=%d", value)
+		return
+	}
+	if err := r.NextNull(); err != nil {
+		t.Errorf("TestLenient:NextNull:err=%s", err.Error())
+		return
+	}
+	if value, err := r.NextInt(); err != nil {
+		t.Errorf("TestLenient:NextInt:err=%s", err.Error())
+		return
+	} else if value != 2 {
+		t.Errorf("TestLenient:NextInt=%d", value)
+		return
+	}
+	if err := r.NextNull(); err != nil {
+		t.Errorf("TestLenient:NextNull:err=%s", err.Error())
+		return
+	}
+	if err := r.EndArray(); err != nil {
+		t.Errorf("TestLenient:EndArray:err=%s", err.Error())
+		return
+	}
+	if name, err := r.NextName(); err != nil {
+		t.Errorf("TestLenient:NextName:err=%s", err.Error())
+		return
+	} else if name != "e" {
+		t.Errorf("TestLenient:NextName:name=%s", name)
+		return
+	}
+	if value, err := r.NextFloat64(); err != nil {
+		t.Errorf("TestLenient:NextFloat64:err=%s", err.Error())
+		return
+	} else if !math.IsInf(value, -1) {
+		t.Errorf("TestLenient:NextFloat64=%g", value)
+		return
+	}
+	if err := r.EndObject(); err != nil {
+		t.Errorf("TestLenient:EndObject:err=%s", err.Error())
+		return
+	}
+	for _, input := range []string{"// comment\n1", "'a'", "[1;2]", "{a=1}", "NaN"} {
+		r = NewReader(bytes.NewBufferString(input))
+		if err := r.SkipValue(); !errors.Is(err, InvalidInput) {
+			if err == nil {
+				t.Errorf("TestLenient:%s:SkipValue:err=nil", input)
+			} else {
+				t.Errorf("TestLenient:%s:SkipValue:err=%s", input, err.Error())
+			}
+			return
+		}
+		r = NewReader(bytes.NewBufferString(input))
+		r.SetLenient(true)
+		if err := r.SkipValue(); err != nil {
+			t.Errorf("TestLenient:%s:SkipValue:err=%s", input, err.Error())
+			return
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	r := NewReader(bytes.NewBufferString(`{"tree":{"kids":[1,{"name":"a"},[]],"skip":[{}]},"end":null}`))
+	expectPath := func(expected string) bool {
+		if path := r.Path(); path != expected {
+			t.Errorf("TestPath:Path:expected=%s,path=%s", expected, path)
+			return false
+		}
+		return true
+	}
+	if !expectPath("$") {
+		return
+	}
+	if err := r.BeginObject(); err != nil {
+		t.Errorf("TestPath:BeginObject:err=%s", err.Error())
+		return
+	}
+	if _, err := r.NextName(); err != nil {
+		t.Errorf("TestPath:NextName:err=%s", err.Error())
+		return
+	}
+	if !expectPath("$.tree") {
+		return
+	}
+	if err := r.BeginObject(); err != nil {
+		t.Errorf("TestPath:BeginObject:err=%s", err.Error())
+		return
+	}
+	if _, err := r.NextName(); err != nil {
+		t.Errorf("TestPath:NextName:err=%s", err.Error())
+		return
+	}
+	if err := r.BeginArray(); err != nil {
+		t.Errorf("TestPath:BeginArray:err=%s", err.Error())
+		return
+	}
+	if !expectPath("$.tree.kids[0]") {
+		return
+	}
+	if _, err := r.NextInt(); err != nil {
+		t.Errorf("TestPath:NextInt:err=%s", err.Error())
+		return
+	}
+	if err := r.BeginObject(); err != nil {
+		t.Errorf("TestPath:BeginObject:err=%s", err.Error())
+		return
+	}
+	if _, err := r.NextName(); err != nil {
+		t.Errorf("TestPath:NextName:err=%s", err.Error())
+		return
+	}
+	if !expectPath("$.tree.kids[1].name") {
+		return
+	}
+	if _, err := r.NextString(); err != nil {
+		t.Errorf("TestPath:NextString:err=%s", err.Error())
+		return
+	}
+	if err := r.EndObject(); err != nil {
+		t.Errorf("TestPath:EndObject:err=%s", err.Error())
+		return
+	}
+	if !expectPath("$.tree.kids[2]") {
+		return
+	}
+	if _, err := r.NextBoolean(); err != IllegalState {
+		if err == nil {
+			t.Errorf("TestPath:NextBoolean:err=nil")
+		} else {
+			t.Errorf("TestPath:NextBoolean:err=%s", err.Error())
+		}
+		return
+	}
+	if !expectPath("$.tree.kids[2]") {
+		return
+	}
+	if err := r.SkipValue(); err != nil {
+		t.Errorf("TestPath:SkipValue:err=%s", err.Error())
+		return
+	}
+	if !expectPath("$.tree.kids[3]") {
+		return
+	}
+	if err := r.EndArray(); err != nil {
+		t.Errorf("TestPath:EndArray:err=%s", err.Error())
+		return
+	}
+	if !expectPath("$.tree.kids") {
+		return
+	}
+	if _, err := r.NextName(); err != nil {
+		t.Errorf("TestPath:NextName:err=%s", err.Error())
+		return
+	}
+	if err := r.SkipValue(); err != nil {
+		t.Errorf("TestPath:SkipValue:err=%s", err.Error())
+		return
+	}
+	if err := r.EndObject(); err != nil {
+		t.Errorf("TestPath:EndObject:err=%s", err.Error())
+		return
+	}
+	if _, err := r.NextName(); err != nil {
+		t.Errorf("TestPath:NextName:err=%s", err.Error())
+		return
+	}
+	if !expectPath("$.end") {
+		return
+	}
+	if err := r.NextNull(); err != nil {
+		t.Errorf("TestPath:NextNull:err=%s", err.Error())
+		return
+	}
+	if err := r.EndObject(); err != nil {
+		t.Errorf("TestPath:EndObject:err=%s", err.Error())
+		return
+	}
+	if !expectPath("$") {
+		return
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	for _, test := range []struct {
+		input         string
+		offset        int64
+		line, column  int
+		found         string
+		unexpectedEOF bool
+		expected      string
+	}{
+		{"[1,\n 2,\n x]", 9, 3, 2, "x", false, "value"},
+		{"[\"a\\q\"]", 3, 1, 4, "\\q", false, ""},
+		{"[\"\\u12G4\"]", 6, 1, 7, "G", false, ""},
+		{"\n\n[nul", 6, 3, 5, "", true, `"null"`},
+		{"{\"a\"\n\n1}", 6, 3, 1, "1", false, ""},
+		{"1.x", 2, 1, 3, "x", false, ""},
+		{"tru]", 3, 1, 4, "]", false, `"true"`},
+		{"[fals", 5, 1, 6, "", true, `"false"`},
+	} {
+		r := NewReader(bytes.NewBufferString(test.input))
+		err := r.SkipValue()
+		if !errors.Is(err, InvalidInput) {
+			if err == nil {
+				t.Errorf("TestSyntaxError:%q:SkipValue:err=nil", test.input)
+			} else {
+				t.Errorf("TestSyntaxError:%q:SkipValue:err=%s", test.input, err.Error())
+			}
+			continue
+		}
+		var syntaxError *SyntaxError
+		if !errors.As(err, &syntaxError) {
+			t.Errorf("TestSyntaxError:%q:SkipValue:err=%s", test.input, err.Error())
+			continue
+		}
+		if syntaxError.Offset != test.offset || syntaxError.Line != test.line || syntaxError.Column != test.column || string(syntaxError.Found) != test.found {
+			t.Errorf("TestSyntaxError:%q:SkipValue:err=%s", test.input, err.Error())
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) != test.unexpectedEOF {
+			t.Errorf("TestSyntaxError:%q:SkipValue:err=%s", test.input, err.Error())
+		}
+		if test.expected != "" && syntaxError.Expected != test.expected {
+			t.Errorf("TestSyntaxError:%q:SkipValue:err=%s", test.input, err.Error())
+		}
+	}
+}
+
+func TestStickyError(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		read  func(r *Reader) error
+	}{
+		{`[tru]`, func(r *Reader) error { _, err := r.NextBoolean(); return err }},
+		{`["ab`, func(r *Reader) error { _, err := r.NextString(); return err }},
+		{`[1.x]`, func(r *Reader) error { _, err := r.NextInt(); return err }},
+		{`[{"a":1}, x]`, func(r *Reader) error { return r.SkipValueUnchecked() }},
+	} {
+		r := NewReader(strings.NewReader(test.input + "\n[2]"))
+		if err := r.BeginArray(); err != nil {
+			t.Errorf("TestStickyError:%s:BeginArray:err=%s", test.input, err.Error())
+			continue
+		}
+		err := test.read(r)
+		for err == nil {
+			err = test.read(r)
+		}
+		if !errors.Is(err, InvalidInput) {
+			t.Errorf("TestStickyError:%s:err=%s", test.input, err.Error())
+		}
+		// Every method returns the same error until Recover.
+		if err2 := test.read(r); err2 != err {
+			t.Errorf("TestStickyError:%s:err=%v", test.input, err2)
+		}
+		if _, err2 := r.Peek(); err2 != err {
+			t.Errorf("TestStickyError:%s:Peek:err=%v", test.input, err2)
+		}
+		if err2 := r.EndArray(); err2 != err {
+			t.Errorf("TestStickyError:%s:EndArray:err=%v", test.input, err2)
+		}
+		if _, _, err := r.Recover(); err != nil {
+			t.Errorf("TestStickyError:%s:Recover:err=%s", test.input, err.Error())
+		}
+		if s := readTokens(r); s != `[ 9"2" ] ` {
+			t.Errorf("TestStickyError:%s:s=%s", test.input, s)
+		}
+	}
+}
+
+func TestMismatched(t *testing.T) {
+	for _, input := range []string{`[1}`, `{"a":1]`, `["a":1]`, `{"a"}`, `{"a":}`, `{"a" "b"}`, `[1,]`, `{"a":1,}`, `[,1]`, `{,}`, `[1 2]`, `01`, `[[]`, `2.e3`, `-.5`, `-e5`, `-0.E1`, `[1.e]`} {
+		r := NewReader(bytes.NewBufferString(input))
+		if err := r.SkipValue(); !errors.Is(err, InvalidInput) {
+			if err == nil {
+				t.Errorf("TestMismatched:%s:SkipValue:err=nil", input)
+			} else {
+				t.Errorf("TestMismatched:%s:SkipValue:err=%s", input, err.Error())
+			}
+		}
+	}
+	r := NewReader(bytes.NewBufferString(`["a":1]`))
+	if err := r.BeginArray(); err != nil {
+		t.Errorf("TestMismatched:BeginArray:err=%s", err.Error())
+		return
+	}
+	if _, err := r.NextName(); err != IllegalState {
+		if err == nil {
+			t.Errorf("TestMismatched:NextName:err=nil")
+		} else {
+			t.Errorf("TestMismatched:NextName:err=%s", err.Error())
+		}
+		return
+	}
+	if value, err := r.NextString(); err != nil {
+		t.Errorf("TestMismatched:NextString:err=%s", err.Error())
+		return
+	} else if value != "a" {
+		t.Errorf("TestMismatched:NextString=%s", value)
+		return
+	}
+	if _, err := r.HasNext(); !errors.Is(err, InvalidInput) {
+		if err == nil {
+			t.Errorf("TestMismatched:HasNext:err=nil")
+		} else {
+			t.Errorf("TestMismatched:HasNext:err=%s", err.Error())
+		}
+		return
+	}
+}
+
+func TestWriteIllegalState(t *testing.T) {
+	for i, write := range []func(w *Writer) error{
+		func(w *Writer) error {
+			return w.EndObject()
+		},
+		func(w *Writer) error {
+			if err := w.BeginArray(); err != nil {
+				return err
+			}
+			return w.Name("a")
+		},
+		func(w *Writer) error {
+			if err := w.BeginObject(); err != nil {
+				return err
+			}
+			return w.NullValue()
+		},
+		func(w *Writer) error {
+			if err := w.BeginObject(); err != nil {
+				return err
+			}
+			if err := w.Name("a"); err != nil {
+				return err
+			}
+			if err := w.NullValue(); err != nil {
+				return err
+			}
+			return w.NullValue()
+		},
+		func(w *Writer) error {
+			if err := w.BeginObject(); err != nil {
+				return err
+			}
+			if err := w.Name("a"); err != nil {
+				return err
+			}
+			return w.Name("b")
+		},
+		func(w *Writer) error {
+			if err := w.BeginObject(); err != nil {
+				return err
+			}
+			if err := w.Name("a"); err != nil {
+				return err
+			}
+			return w.EndObject()
+		},
+		func(w *Writer) error {
+			if err := w.BeginArray(); err != nil {
+				return err
+			}
+			return w.EndObject()
+		},
+		func(w *Writer) error {
+			return w.Close()
+		},
+		func(w *Writer) error {
+			if err := w.BeginArray(); err != nil {
+				return err
+			}
+			return w.Close()
+		},
+		func(w *Writer) error {
+			if err := w.NullValue(); err != nil {
+				return err
+			}
+			if err := w.Close(); err != nil {
+				return err
+			}
+			return w.NullValue()
+		},
+	} {
+		buf := bytes.Buffer{}
+		if err := write(NewWriter(&buf)); err != IllegalState {
+			if err == nil {
+				t.Errorf("TestWriteIllegalState:%d:err=nil", i)
+			} else {
+				t.Errorf("TestWriteIllegalState:%d:err=%s", i, err.Error())
+			}
+		}
+	}
+	buf := bytes.Buffer{}
+	w := NewWriter(&buf)
+	if err := w.BeginObject(); err != nil {
+		t.Errorf("TestWriteIllegalState:BeginObject:err=%s", err.Error())
+		return
+	}
+	if err := w.EndObject(); err != nil {
+		t.Errorf("TestWriteIllegalState:EndObject:err=%s", err.Error())
+		return
+	}
+	// Only one top-level value may be written by default.
+	if err := w.BeginObject(); err != IllegalState {
+		t.Errorf("TestWriteIllegalState:BeginObject:err=%v", err)
+		return
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("TestWriteIllegalState:Close:err=%s", err.Error())
+		return
+	}
+	if s := buf.String(); s != "{}" {
+		t.Errorf("TestWriteIllegalState:expected={},s=%s", s)
+		return
+	}
+}
+
+func TestWriteIndent(t *testing.T) {
+	buf := bytes.Buffer{}
+	w := NewWriter(&buf)
+	w.SetIndent(">", "  ")
+	if err := w.BeginObject(); err != nil {
+		t.Errorf("TestWriteIndent:BeginObject:err=%s", err.Error())
+		return
+	}
+	if err := w.Name("a"); err != nil {
+		t.Errorf("TestWriteIndent:Name:err=%s", err.Error())
+		return
+	}
+	if err := w.BeginArray(); err != nil {
+		t.Errorf("TestWriteIndent:BeginArray:err=%s", err.Error())
+		return
+	}
+	if err := w.IntValue(1); err != nil {
+		t.Errorf("TestWriteIndent:IntValue:err=%s", err.Error())
+		return
+	}
+	if err := w.BeginArray(); err != nil {
+		t.Errorf("TestWriteIndent:BeginArray:err=%s", err.Error())
+		return
+	}
+	if err := w.EndArray(); err != nil {
+		t.Errorf("TestWriteIndent:EndArray:err=%s", err.Error())
+		return
+	}
+	if err := w.BeginObject(); err != nil {
+		t.Errorf("TestWriteIndent:BeginObject:err=%s", err.Error())
+		return
+	}
+	if err := w.EndObject(); err != nil {
+		t.Errorf("TestWriteIndent:EndObject:err=%s", err.Error())
+		return
+	}
+	if err := w.EndArray(); err != nil {
+		t.Errorf("TestWriteIndent:EndArray:err=%s", err.Error())
+		return
+	}
+	if err := w.Name("b"); err != nil {
+		t.Errorf("TestWriteIndent:Name:err=%s", err.Error())
+		return
+	}
+	if err := w.BeginObject(); err != nil {
+		t.Errorf("TestWriteIndent:BeginObject:err=%s", err.Error())
+		return
+	}
+	if err := w.Name("c"); err != nil {
+		t.Errorf("TestWriteIndent:Name:err=%s", err.Error())
+		return
+	}
+	if err := w.NullValue(); err != nil {
+		t.Errorf("TestWriteIndent:NullValue:err=%s", err.Error())
+		return
+	}
+	if err := w.EndObject(); err != nil {
+		t.Errorf("TestWriteIndent:EndObject:err=%s", err.Error())
+		return
+	}
+	if err := w.EndObject(); err != nil {
+		t.Errorf("TestWriteIndent:EndObject:err=%s", err.Error())
+		return
+	}
+	expected := "{\n>  \"a\": [\n>    1,\n>    [],\n>    {}\n>  ],\n>  \"b\": {\n>    \"c\": null\n>  }\n>}"
+	if s := buf.String(); s != expected {
+		t.Errorf("TestWriteIndent:expected=%s,s=%s", expected, s)
+		return
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	for _, test := range []struct {
+		input, replaced string
+	}{
+		{"\"a\xffb\"", "a\ufffdb"},
+		{"\"a\xc3\"", "a\ufffd"},
+		{"\"\xed\xa0\x80\"", "\ufffd"},
+		{"\"\xe0\x80\x80\"", "\ufffd"},
+		{"\"\xf4\x90\x80\x80\"", "\ufffd"},
+		{"\"\xe2\x82a\"", "\ufffda"},
+	} {
+		r := NewReader(bytes.NewBufferString(test.input))
+		if _, err := r.NextString(); !errors.Is(err, InvalidInput) {
+			if err == nil {
+				t.Errorf("TestInvalidUTF8:%q:NextString:err=nil", test.input)
+			} else {
+				t.Errorf("TestInvalidUTF8:%q:NextString:err=%s", test.input, err.Error())
+			}
+		}
+		r = NewReader(bytes.NewBufferString(test.input))
+		r.SetUTF8Policy(ReplaceInvalidUTF8)
+		if value, err := r.NextString(); err != nil {
+			t.Errorf("TestInvalidUTF8:%q:NextString:err=%s", test.input, err.Error())
+		} else if value != test.replaced {
+			t.Errorf("TestInvalidUTF8:%q:NextString=%q", test.input, value)
+		}
+		r = NewReader(bytes.NewBufferString(test.input))
+		r.SetUTF8Policy(PassInvalidUTF8)
+		if value, err := r.NextString(); err != nil {
+			t.Errorf("TestInvalidUTF8:%q:NextString:err=%s", test.input, err.Error())
+		} else if value != test.input[1:len(test.input)-1] {
+			t.Errorf("TestInvalidUTF8:%q:NextString=%q", test.input, value)
+		}
+	}
+	r := NewReader(bytes.NewBufferString("\"é€\U0001D11E\""))
+	if value, err := r.NextString(); err != nil {
+		t.Errorf("TestInvalidUTF8:NextString:err=%s", err.Error())
+	} else if value != "é€\U0001D11E" {
+		t.Errorf("TestInvalidUTF8:NextString=%q", value)
+	}
+}
+
+func TestReadInteger(t *testing.T) {
+	r := NewReader(bytes.NewBufferString(`[127,-128,32767,-32768,2147483647,-2147483648,255,65535,4294967295,18446744073709551615,-0,"12"]`))
+	if err := r.BeginArray(); err != nil {
+		t.Errorf("TestReadInteger:BeginArray:err=%s", err.Error())
+		return
+	}
+	if value, err := r.NextInt8(); err != nil || value != 127 {
+		t.Errorf("TestReadInteger:NextInt8=%d,err=%v", value, err)
+	}
+	if value, err := r.NextInt8(); err != nil || value != -128 {
+		t.Errorf("TestReadInteger:NextInt8=%d,err=%v", value, err)
+	}
+	if value, err := r.NextInt16(); err != nil || value != 32767 {
+		t.Errorf("TestReadInteger:NextInt16=%d,err=%v", value, err)
+	}
+	if value, err := r.NextInt16(); err != nil || value != -32768 {
+		t.Errorf("TestReadInteger:NextInt16=%d,err=%v", value, err)
+	}
+	if value, err := r.NextInt32(); err != nil || value != 2147483647 {
+		t.Errorf("TestReadInteger:NextInt32=%d,err=%v", value, err)
+	}
+	if value, err := r.NextInt32(); err != nil || value != -2147483648 {
+		t.Errorf("TestReadInteger:NextInt32=%d,err=%v", value, err)
+	}
+	if value, err := r.NextUint8(); err != nil || value != 255 {
+		t.Errorf("TestReadInteger:NextUint8=%d,err=%v", value, err)
+	}
+	if value, err := r.NextUint16(); err != nil || value != 65535 {
+		t.Errorf("TestReadInteger:NextUint16=%d,err=%v", value, err)
+	}
+	if value, err := r.NextUint32(); err != nil || value != 4294967295 {
+		t.Errorf("TestReadInteger:NextUint32=%d,err=%v", value, err)
+	}
+	if value, err := r.NextUint64(); err != nil || value != math.MaxUint64 {
+		t.Errorf("TestReadInteger:NextUint64=%d,err=%v", value, err)
+	}
+	if value, err := r.NextUint(); err != nil || value != 0 {
+		t.Errorf("TestReadInteger:NextUint=%d,err=%v", value, err)
+	}
+	if value, err := r.NextUint(); err != nil || value != 12 {
+		t.Errorf("TestReadInteger:NextUint=%d,err=%v", value, err)
+	}
+	if err := r.EndArray(); err != nil {
+		t.Errorf("TestReadInteger:EndArray:err=%s", err.Error())
+		return
+	}
+	r = NewReader(bytes.NewBufferString(`{"a":[128,-1,256,18446744073709551616,-9223372036854775809]}`))
+	if err := r.BeginObject(); err != nil {
+		t.Errorf("TestReadInteger:BeginObject:err=%s", err.Error())
+		return
+	}
+	if _, err := r.NextName(); err != nil {
+		t.Errorf("TestReadInteger:NextName:err=%s", err.Error())
+		return
+	}
+	if err := r.BeginArray(); err != nil {
+		t.Errorf("TestReadInteger:BeginArray:err=%s", err.Error())
+		return
+	}
+	for i, next := range []func() error{
+		func() error {
+			_, err := r.NextInt8()
+			return err
+		},
+		func() error {
+			_, err := r.NextUint()
+			return err
+		},
+		func() error {
+			_, err := r.NextUint8()
+			return err
+		},
+		func() error {
+			_, err := r.NextUint64()
+			return err
+		},
+		func() error {
+			_, err := r.NextInt()
+			return err
+		},
+	} {
+		err := next()
+		var rangeError *RangeError
+		if !errors.As(err, &rangeError) || !errors.Is(err, strconv.ErrRange) {
+			if err == nil {
+				t.Errorf("TestReadInteger:%d:err=nil", i)
+			} else {
+				t.Errorf("TestReadInteger:%d:err=%s", i, err.Error())
+			}
+			continue
+		}
+		if path := fmt.Sprintf("$.a[%d]", i); rangeError.Path != path {
+			t.Errorf("TestReadInteger:%d:Path=%s", i, rangeError.Path)
+		}
+	}
+}
+
+func TestNextRawValue(t *testing.T) {
+	r := NewReader(bytes.NewBufferString(`{"a": [1, {"b" : "x\u0041"} ], "c":"\u00e9", "d": 1.5e3 , "e":true} ["f"] -0`))
+	if err := r.BeginObject(); err != nil {
+		t.Errorf("TestNextRawValue:BeginObject:err=%s", err.Error())
+		return
+	}
+	for _, expected := range []string{`[1, {"b" : "x\u0041"} ]`, `"\u00e9"`, `1.5e3`, `true`} {
+		if _, err := r.NextName(); err != nil {
+			t.Errorf("TestNextRawValue:NextName:err=%s", err.Error())
+			return
+		}
+		if _, err := r.Peek(); err != nil {
+			t.Errorf("TestNextRawValue:Peek:err=%s", err.Error())
+			return
+		}
+		if raw, err := r.NextRawValue(); err != nil {
+			t.Errorf("TestNextRawValue:NextRawValue:err=%s", err.Error())
+			return
+		} else if string(raw) != expected {
+			t.Errorf("TestNextRawValue:NextRawValue:expected=%s,raw=%s", expected, raw)
+			return
+		}
+	}
+	if _, err := r.NextRawValue(); err != IllegalState {
+		if err == nil {
+			t.Errorf("TestNextRawValue:NextRawValue:err=nil")
+		} else {
+			t.Errorf("TestNextRawValue:NextRawValue:err=%s", err.Error())
+		}
+		return
+	}
+	if err := r.EndObject(); err != nil {
+		t.Errorf("TestNextRawValue:EndObject:err=%s", err.Error())
+		return
+	}
+	if raw, err := r.NextRawValue(); err != nil {
+		t.Errorf("TestNextRawValue:NextRawValue:err=%s", err.Error())
+		return
+	} else if string(raw) != `["f"]` {
+		t.Errorf("TestNextRawValue:NextRawValue=%s", raw)
+		return
+	}
+	if raw, err := r.NextRawValue(); err != nil {
+		t.Errorf("TestNextRawValue:NextRawValue:err=%s", err.Error())
+		return
+	} else if string(raw) != `-0` {
+		t.Errorf("TestNextRawValue:NextRawValue=%s", raw)
+		return
+	}
+	r = NewReader(bytes.NewBufferString(`[1,{"a":}]`))
+	if _, err := r.NextRawValue(); !errors.Is(err, InvalidInput) {
+		if err == nil {
+			t.Errorf("TestNextRawValue:NextRawValue:err=nil")
+		} else {
+			t.Errorf("TestNextRawValue:NextRawValue:err=%s", err.Error())
+		}
+		return
+	}
+}
+
+// Read all of the tokens from r, returning a description of them and of
+// any error.
+func readTokens(r *Reader) string {
+	var buf bytes.Buffer
+	for {
+		token, err := r.Peek()
+		if err == nil {
+			switch token {
+			case BEGIN_ARRAY:
+				err = r.BeginArray()
+				buf.WriteString("[ ")
+			case END_ARRAY:
+				err = r.EndArray()
+				buf.WriteString("] ")
+			case BEGIN_OBJECT:
+				err = r.BeginObject()
+				buf.WriteString("{ ")
+			case END_OBJECT:
+				err = r.EndObject()
+				buf.WriteString("} ")
+			case NAME:
+				var name string
+				name, err = r.NextName()
+				fmt.Fprintf(&buf, "%q: ", name)
+			case STRING, NUMBER:
+				var value string
+				value, err = r.NextString()
+				fmt.Fprintf(&buf, "%d%q ", token, value)
+			case BOOLEAN:
+				var value bool
+				value, err = r.NextBoolean()
+				fmt.Fprintf(&buf, "%t ", value)
+			case NULL:
+				err = r.NextNull()
+				buf.WriteString("null ")
+			case END_DOCUMENT:
+				return buf.String()
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(&buf, "err=%s path=%s", err.Error(), r.Path())
+			return buf.String()
+		}
+	}
+}
+
+func TestBytesReader(t *testing.T) {
+	for _, input := range []string{
+		`{"a": [1, {"b" : "xA"} ], "c":"é", "d": 1.5e3 , "e":true} ["f"] -0`,
+		"[1,\n 2,\n x]",
+		"[\"a\\q\"]",
+		"\n\n[nul",
+		"{\"a\"\n\n1}",
+		"1.x",
+		"\"a\xffb\"",
+		"\"é€\U0001D11E\"",
+		"\"\x08\"",
+		`"""`,
+		`"abc`,
+		`[1}`, `{"a":}`, `[1 2]`, `01`, `[[]`, `-`, `1e`, `[1.5,`,
+		"\ufeff[\"bom\"]",
+		"\x00[\x00\"\x00a\x00\"\x00]",
+	} {
+		expected := readTokens(NewReader(bytes.NewBufferString(input)))
+		if s := readTokens(NewBytesReader([]byte(input))); s != expected {
+			t.Errorf("TestBytesReader:%q:expected=%s,s=%s", input, expected, s)
+		}
+	}
+
+	input := []byte(`{"name":"value","esc\"aped":"A","num":-1.5}`)
+	r := NewBytesReader(input)
+	if err := r.BeginObject(); err != nil {
+		t.Errorf("TestBytesReader:BeginObject:err=%s", err.Error())
+		return
+	}
+	for _, test := range []struct {
+		name, value string
+		// The offset of the name in the input, or -1 if it is copied.
+		offset int
+	}{
+		{"name", "value", 2},
+		{"esc\"aped", "A", -1},
+		{"num", "-1.5", 33},
+	} {
+		name, err := r.NextNameBytes()
+		if err != nil {
+			t.Errorf("TestBytesReader:NextNameBytes:err=%s", err.Error())
+			return
+		} else if string(name) != test.name {
+			t.Errorf("TestBytesReader:NextNameBytes:expected=%s,name=%s", test.name, name)
+		}
+		if path := r.Path(); path != "$."+test.name {
+			t.Errorf("TestBytesReader:Path=%s", path)
+		}
+		value, err := r.NextStringBytes()
+		if err != nil {
+			t.Errorf("TestBytesReader:NextStringBytes:err=%s", err.Error())
+			return
+		} else if string(value) != test.value {
+			t.Errorf("TestBytesReader:NextStringBytes:expected=%s,value=%s", test.value, value)
+		}
+		for i := range input {
+			if &input[i] == &name[0] && i != test.offset {
+				t.Errorf("TestBytesReader:NextNameBytes:%s:offset=%d", name, i)
+			}
+		}
+		if test.offset >= 0 && &input[test.offset] != &name[0] {
+			t.Errorf("TestBytesReader:NextNameBytes:%s:copied", name)
+		}
+		// Appending must not overwrite the input that follows.
+		_ = append(name, "XXX"...)
+		_ = append(value, "XXX"...)
+	}
+	if err := r.EndObject(); err != nil {
+		t.Errorf("TestBytesReader:EndObject:err=%s", err.Error())
+		return
+	}
+	if s := string(input); s != `{"name":"value","esc\"aped":"A","num":-1.5}` {
+		t.Errorf("TestBytesReader:input=%s", s)
+	}
+
+	input = []byte(`["ab","cd"]`)
+	r = NewBytesReader(input)
+	if tokens, err := r.PeekN(3); err != nil || len(tokens) != 3 {
+		t.Errorf("TestBytesReader:PeekN:tokens=%v,err=%v", tokens, err)
+		return
+	}
+	if err := r.BeginArray(); err != nil {
+		t.Errorf("TestBytesReader:BeginArray:err=%s", err.Error())
+		return
+	}
+	value, err := r.NextStringBytes()
+	if err != nil || string(value) != "ab" {
+		t.Errorf("TestBytesReader:NextStringBytes:value=%s,err=%v", value, err)
+		return
+	}
+	_ = append(value, "XXX"...)
+	if value, err := r.NextString(); err != nil || value != "cd" {
+		t.Errorf("TestBytesReader:NextString:value=%s,err=%v", value, err)
+	}
+
+	r = NewBytesReader([]byte(`[{"a" : [1, "b"] }, 2]`))
+	if err := r.BeginArray(); err != nil {
+		t.Errorf("TestBytesReader:BeginArray:err=%s", err.Error())
+		return
+	}
+	for _, expected := range []string{`{"a" : [1, "b"] }`, `2`} {
+		if raw, err := r.NextRawValue(); err != nil {
+			t.Errorf("TestBytesReader:NextRawValue:err=%s", err.Error())
+			return
+		} else if string(raw) != expected {
+			t.Errorf("TestBytesReader:NextRawValue:expected=%s,raw=%s", expected, raw)
+		}
+	}
+
+	r = NewBytesReader([]byte("\"a\xffb\""))
+	r.SetUTF8Policy(ReplaceInvalidUTF8)
+	if value, err := r.NextStringBytes(); err != nil {
+		t.Errorf("TestBytesReader:NextStringBytes:err=%s", err.Error())
+	} else if string(value) != "a\ufffdb" {
+		t.Errorf("TestBytesReader:NextStringBytes=%q", value)
+	}
+
+	r = NewReader(bytes.NewBufferString(`{"a":"b"}`))
+	if err := r.BeginObject(); err != nil {
+		t.Errorf("TestBytesReader:BeginObject:err=%s", err.Error())
+		return
+	}
+	if name, err := r.NextNameBytes(); err != nil {
+		t.Errorf("TestBytesReader:NextNameBytes:err=%s", err.Error())
+	} else if string(name) != "a" {
+		t.Errorf("TestBytesReader:NextNameBytes=%s", name)
+	}
+	if value, err := r.NextStringBytes(); err != nil {
+		t.Errorf("TestBytesReader:NextStringBytes:err=%s", err.Error())
+	} else if string(value) != "b" {
+		t.Errorf("TestBytesReader:NextStringBytes=%s", value)
+	}
+}
+
+func TestNextDocument(t *testing.T) {
+	for _, test := range []struct {
+		mode      DocumentMode
+		input     string
+		documents []string
+	}{
+		{ValueStream, ` {"a":1} [2]` + "\n" + `3 "x" `, []string{`{ "a": 9"1" } [ 9"2" ] 9"3" 10"x" `}},
+		{MultipleDocuments, ` {"a":1} [2]` + "\n" + `3 "x" `, []string{`{ "a": 9"1" } `, `[ 9"2" ] `, `9"3" `, `10"x" `}},
+		{MultipleDocuments, "", nil},
+		{JSONLines, `{"a":1}` + "\r\n" + ` [2] ` + "\n3\n", []string{`{ "a": 9"1" } `, `[ 9"2" ] `, `9"3" `}},
+		{JSONLines, "1\n2 3\n", []string{`9"1" `, `9"2" err=rgo: Invalid input at line 2, column 3 (offset 4): found "3", expected end of line path=$`}},
+		{JSONLines, "1\n[2,\n3]\n", []string{`9"1" `, `[ 9"2" err=rgo: Invalid input at line 2, column 4 (offset 5): found "\n", expected record on one line path=$[1]`}},
+		{JSONLines, "1\n\n2\n", []string{`9"1" `, `err=rgo: Invalid input at line 2, column 1 (offset 2): found "\n", expected record on one line path=$`}},
+	} {
+		for _, newReader := range []func() *Reader{
+			func() *Reader { return NewReader(bytes.NewBufferString(test.input)) },
+			func() *Reader { return NewBytesReader([]byte(test.input)) },
+		} {
+			r := newReader()
+			r.SetDocumentMode(test.mode)
+			var documents []string
+			for {
+				more, err := r.NextDocument()
+				if err != nil {
+					documents = append(documents, "err="+err.Error()+" path="+r.Path())
+					break
+				} else if !more {
+					break
+				}
+				document := readTokens(r)
+				documents = append(documents, document)
+				if strings.Contains(document, "err=") {
+					break
+				}
+			}
+			if fmt.Sprint(documents) != fmt.Sprint(test.documents) {
+				t.Errorf("TestNextDocument:%q:expected=%q,documents=%q", test.input, test.documents, documents)
+			}
+		}
+	}
+
+	r := NewReader(bytes.NewBufferString(`[1] 2`))
+	r.SetDocumentMode(MultipleDocuments)
+	if err := r.BeginArray(); err != nil {
+		t.Errorf("TestNextDocument:BeginArray:err=%s", err.Error())
+		return
+	}
+	if _, err := r.NextDocument(); err != IllegalState {
+		t.Errorf("TestNextDocument:NextDocument:err=%v", err)
+	}
+	if _, err := r.NextInt(); err != nil {
+		t.Errorf("TestNextDocument:NextInt:err=%s", err.Error())
+		return
+	}
+	if _, err := r.NextDocument(); err != IllegalState {
+		t.Errorf("TestNextDocument:NextDocument:err=%v", err)
+	}
+	if err := r.EndArray(); err != nil {
+		t.Errorf("TestNextDocument:EndArray:err=%s", err.Error())
+		return
+	}
+	if hasNext, err := r.HasNext(); err != nil || hasNext {
+		t.Errorf("TestNextDocument:HasNext=%t,err=%v", hasNext, err)
+	}
+	if more, err := r.NextDocument(); err != nil || !more {
+		t.Errorf("TestNextDocument:NextDocument=%t,err=%v", more, err)
+	}
+	if value, err := r.NextInt(); err != nil || value != 2 {
+		t.Errorf("TestNextDocument:NextInt=%d,err=%v", value, err)
+	}
+	if more, err := r.NextDocument(); err != nil || more {
+		t.Errorf("TestNextDocument:NextDocument=%t,err=%v", more, err)
+	}
+}
+
+func TestJSONSequence(t *testing.T) {
+	for _, test := range []struct {
+		input     string
+		documents []string
+	}{
+		{"\x1e{\"a\":1}\n\x1e[2]\n\x1e\x1e3\n\x1e\"x\"\x1e 4 \n", []string{`{ "a": 9"1" } `, `[ 9"2" ] `, `9"3" `, `10"x" `, `9"4" `}},
+		{"", nil},
+		{"\x1e123\x1e[1]\n", []string{`err=rgo: Invalid input at line 1, column 5 (offset 4): found "\x1e", expected whitespace after possibly truncated value path=$`, `[ 9"1" ] `}},
+		{"\x1etrue\x1enull\n", []string{`err=rgo: Invalid input at line 1, column 6 (offset 5): found "\x1e", expected whitespace after possibly truncated value path=$`, `null `}},
+		{"\x1e{\"a\":\n\x1etrue\n\x1e[1 2]\n\x1e\n", []string{`{ "a": err=rgo: Invalid input at line 2, column 1 (offset 7): found "\x1e", expected value path=$.a`, `true `, `[ 9"1" err=rgo: Invalid input at line 3, column 5 (offset 17): found "2", expected ',' or ']' path=$[1]`}},
+		{"x\x1e1\n", []string{`err=rgo: Invalid input at line 1, column 1 (offset 0): found "x", expected record separator path=$`, `9"1" `}},
+		{"\x1e1 2\n\x1e3\n", []string{`9"1" err=rgo: Invalid input at line 1, column 4 (offset 3): found "2", expected record separator path=$`, `9"3" `}},
+		{"\x1e[1", []string{`[ 9"1" err=rgo: Invalid input at line 1, column 4 (offset 3): found end of input, expected ',' or ']' path=$[1]`}},
+	} {
+		for _, newReader := range []func() *Reader{
+			func() *Reader { return NewReader(bytes.NewBufferString(test.input)) },
+			func() *Reader { return NewBytesReader([]byte(test.input)) },
+		} {
+			r := newReader()
+			r.SetDocumentMode(JSONSequence)
+			var documents []string
+			for i := 0; i < 10; i++ {
+				more, err := r.NextDocument()
+				if err != nil {
+					documents = append(documents, "err="+err.Error()+" path="+r.Path())
+					continue
+				} else if !more {
+					break
+				}
+				documents = append(documents, readTokens(r))
+			}
+			if fmt.Sprint(documents) != fmt.Sprint(test.documents) {
+				t.Errorf("TestJSONSequence:%q:expected=%q,documents=%q", test.input, test.documents, documents)
+			}
+		}
+	}
+
+	for _, test := range []struct {
+		mode     DocumentMode
+		expected string
+	}{
+		{JSONLines, "{\"a\":[1]}\n2\n\"x\"\n"},
+		{JSONSequence, "\x1e{\"a\":[1]}\n\x1e2\n\x1e\"x\"\n"},
+	} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		w.SetDocumentMode(test.mode)
+		for _, err := range []error{w.BeginObject(), w.Name("a"), w.BeginArray(), w.IntValue(1), w.EndArray(), w.EndObject(), w.IntValue(2), w.StringValue("x"), w.Close()} {
+			if err != nil {
+				t.Errorf("TestJSONSequence:%d:err=%s", test.mode, err.Error())
+			}
+		}
+		if buf.String() != test.expected {
+			t.Errorf("TestJSONSequence:%d:expected=%q,s=%q", test.mode, test.expected, buf.String())
+		}
+		r := NewReader(&buf)
+		r.SetDocumentMode(test.mode)
+		var documents []string
+		for {
+			more, err := r.NextDocument()
+			if err != nil {
+				t.Errorf("TestJSONSequence:%d:NextDocument:err=%s", test.mode, err.Error())
+				break
+			} else if !more {
+				break
+			}
+			documents = append(documents, readTokens(r))
+		}
+		if s := fmt.Sprint(documents); s != `[{ "a": [ 9"1" ] }  9"2"  10"x" ]` {
+			t.Errorf("TestJSONSequence:%d:documents=%s", test.mode, s)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	for _, test := range []struct {
+		mode      DocumentMode
+		delimiter byte
+		input     string
+		documents []string
+	}{
+		{JSONLines, 0, "{\"a\":1}\n{\"a\":\n[1,2]\n\"unterminated\n3 4\n\"ok\"\n[", []string{`{ "a": 9"1" } `, `{ "a": skipped "{\"a\":"`, `[ 9"1" 9"2" ] `, `skipped "\"unterminated"`, `9"3" skipped "3 4"`, `10"ok" `, `skipped "["`}},
+		{MultipleDocuments, '|', "1 [x| 2|{\"a\":|3", []string{`9"1" `, `[ skipped "[x"`, `9"2" `, `{ "a": skipped "{\"a\":"`, `9"3" `}},
+		{JSONSequence, 0, "\x1e1\n\x1e[tru\x1e\"x\"\n", []string{`9"1" `, `[ skipped "[tru"`, `10"x" `}},
+		{ValueStream, 0, "1 2\n[\n3,x]\n4", []string{`9"1" 9"2" [ 9"3" skipped "[\n3,x]"`, `9"4" `}},
+	} {
+		for _, newReader := range []func() *Reader{
+			func() *Reader { return NewReader(bytes.NewBufferString(test.input)) },
+			func() *Reader { return NewBytesReader([]byte(test.input)) },
+		} {
+			r := newReader()
+			r.SetDocumentMode(test.mode)
+			if test.delimiter != 0 {
+				r.SetRecordDelimiter(test.delimiter)
+			}
+			var documents []string
+			for i := 0; i < 10; i++ {
+				more, err := r.NextDocument()
+				if err == nil && !more {
+					break
+				}
+				document := ""
+				if err == nil {
+					document = readTokens(r)
+				}
+				if err != nil || strings.Contains(document, "err=") {
+					if i := strings.Index(document, "err="); i >= 0 {
+						document = document[:i]
+					}
+					start, end, err := r.Recover()
+					if err != nil {
+						t.Errorf("TestRecover:%q:Recover:err=%s", test.input, err.Error())
+						break
+					}
+					document += fmt.Sprintf("skipped %q", test.input[start:end])
+				}
+				documents = append(documents, document)
+			}
+			if fmt.Sprint(documents) != fmt.Sprint(test.documents) {
+				t.Errorf("TestRecover:%q:expected=%q,documents=%q", test.input, test.documents, documents)
+			}
+		}
+	}
+
+	r := NewReader(bytes.NewBufferString("{\"a\":1}\n  {\"b\":x}\n2\n"))
+	r.SetDocumentMode(JSONLines)
+	for i := 0; i < 2; i++ {
+		if _, err := r.NextDocument(); err != nil {
+			t.Errorf("TestRecover:NextDocument:err=%s", err.Error())
+			return
+		}
+		readTokens(r)
+	}
+	if start, end, err := r.Recover(); err != nil || start != 10 || end != 17 {
+		t.Errorf("TestRecover:Recover:start=%d,end=%d,err=%v", start, end, err)
+	}
+}
+
+func TestPeekN(t *testing.T) {
+	const input = `[{"type":"circle","r":1.5}, {"size":2,"type":"square"}, [[true],"A"], null]`
+	for _, r := range []*Reader{NewReader(bytes.NewBufferString(input)), NewBytesReader([]byte(input))} {
+		if err := r.BeginArray(); err != nil {
+			t.Errorf("TestPeekN:BeginArray:err=%s", err.Error())
+			return
+		}
+		if tokens, err := r.PeekN(3); err != nil {
+			t.Errorf("TestPeekN:PeekN:err=%s", err.Error())
+			return
+		} else if s := fmt.Sprint(tokens); s != "[{2 } {7 type} {10 circle}]" {
+			t.Errorf("TestPeekN:PeekN=%s", s)
+		}
+		if err := r.BeginObject(); err != nil {
+			t.Errorf("TestPeekN:BeginObject:err=%s", err.Error())
+			return
+		}
+		if name, err := r.PeekName(); err != nil || name != "type" {
+			t.Errorf("TestPeekN:PeekName=%s,err=%v", name, err)
+		}
+		if _, err := r.PeekString(); err != IllegalState {
+			t.Errorf("TestPeekN:PeekString:err=%v", err)
+		}
+		if name, err := r.NextName(); err != nil || name != "type" {
+			t.Errorf("TestPeekN:NextName=%s,err=%v", name, err)
+		}
+		if value, err := r.PeekString(); err != nil || value != "circle" {
+			t.Errorf("TestPeekN:PeekString=%s,err=%v", value, err)
+		}
+		if path := r.Path(); path != "$[0].type" {
+			t.Errorf("TestPeekN:Path=%s", path)
+		}
+		if value, err := r.NextString(); err != nil || value != "circle" {
+			t.Errorf("TestPeekN:NextString=%s,err=%v", value, err)
+		}
+		if name, err := r.NextName(); err != nil || name != "r" {
+			t.Errorf("TestPeekN:NextName=%s,err=%v", name, err)
+		}
+		if value, err := r.NextFloat64(); err != nil || value != 1.5 {
+			t.Errorf("TestPeekN:NextFloat64=%g,err=%v", value, err)
+		}
+		if err := r.EndObject(); err != nil {
+			t.Errorf("TestPeekN:EndObject:err=%s", err.Error())
+			return
+		}
+
+		if tokens, err := r.PeekN(MaxLookahead); err != nil {
+			t.Errorf("TestPeekN:PeekN:err=%s", err.Error())
+			return
+		} else if s := fmt.Sprint(tokens); s != "[{2 } {7 size} {9 2} {7 type} {10 square} {6 } {1 } {1 } {3 true} {4 } {10 A} {4 } {8 } {4 } {5 }]" {
+			t.Errorf("TestPeekN:PeekN=%s", s)
+		}
+		if raw, err := r.NextRawValue(); err != nil {
+			t.Errorf("TestPeekN:NextRawValue:err=%s", err.Error())
+			return
+		} else if string(raw) != `{"size":2,"type":"square"}` {
+			t.Errorf("TestPeekN:NextRawValue=%s", raw)
+		}
+		if err := r.BeginArray(); err != nil {
+			t.Errorf("TestPeekN:BeginArray:err=%s", err.Error())
+			return
+		}
+		if err := r.SkipValue(); err != nil {
+			t.Errorf("TestPeekN:SkipValue:err=%s", err.Error())
+			return
+		}
+		if value, err := r.NextStringBytes(); err != nil || string(value) != "A" {
+			t.Errorf("TestPeekN:NextStringBytes=%s,err=%v", value, err)
+		}
+		if path := r.Path(); path != "$[2][2]" {
+			t.Errorf("TestPeekN:Path=%s", path)
+		}
+		if err := r.EndArray(); err != nil {
+			t.Errorf("TestPeekN:EndArray:err=%s", err.Error())
+			return
+		}
+		if tokens, err := r.PeekN(1); err != nil || len(tokens) != 1 || tokens[0].Token != NULL {
+			t.Errorf("TestPeekN:PeekN=%v,err=%v", tokens, err)
+		}
+		if err := r.NextNull(); err != nil {
+			t.Errorf("TestPeekN:NextNull:err=%s", err.Error())
+		}
+		if err := r.EndArray(); err != nil {
+			t.Errorf("TestPeekN:EndArray:err=%s", err.Error())
+		}
+		if tokens, err := r.PeekN(2); err != nil || len(tokens) != 1 || tokens[0].Token != END_DOCUMENT {
+			t.Errorf("TestPeekN:PeekN=%v,err=%v", tokens, err)
+		}
+	}
+
+	r := NewReader(bytes.NewBufferString(`[true,x]`))
+	for _, n := range []int{0, MaxLookahead + 1} {
+		if _, err := r.PeekN(n); err != IllegalArgument {
+			t.Errorf("TestPeekN:PeekN(%d):err=%v", n, err)
+		}
+	}
+	if err := r.BeginArray(); err != nil {
+		t.Errorf("TestPeekN:BeginArray:err=%s", err.Error())
+		return
+	}
+	_, err := r.PeekN(3)
+	if !errors.Is(err, InvalidInput) {
+		t.Errorf("TestPeekN:PeekN:err=%v", err)
+	}
+	// The error is returned until the reader recovers.
+	if _, err2 := r.NextBoolean(); err2 != err {
+		t.Errorf("TestPeekN:NextBoolean:err=%v", err2)
+	}
+}
+
+func TestReaderSize(t *testing.T) {
+	long := strings.Repeat("abcdefghij", 10)
+	for _, input := range []string{
+		`{"a": [1, {"b" : "xA"} ], "c":"é", "d": 1.5e3 , "e":true} ["f"] -0`,
+		`["` + long + `", "` + long + `\n` + long + `", "é` + long + `€"]`,
+		`{"` + long + `": -1234567890.1234567890e+1234567890}`,
+		"[" + strings.Repeat(" \t\r\n", 20) + "1" + strings.Repeat(" ", 30) + "]",
+		`["é𝄞", "` + strings.Repeat(`\"`, 20) + `"]`,
+		`[` + strings.Repeat(`true, false, null, `, 10) + `0]`,
+		"[\"" + long + "\xff" + long + "\"]",
+		"[\"" + long + "\x01\"]",
+		`["` + long,
+		`[12345678901234567890x]`,
+		`// comment ` + long + "\n[/* " + long + ` */ 'single', unquoted` + long + `]`,
+		"\x00[\x00\"\x00" + strings.Repeat("a\x00", 40) + "\"\x00]",
+	} {
+		for _, lenient := range []bool{false, true} {
+			r := NewBytesReader([]byte(input))
+			r.SetLenient(lenient)
+			expected := readTokens(r)
+			for _, newReader := range []func() *Reader{
+				func() *Reader { return NewReader(strings.NewReader(input)) },
+				func() *Reader { return NewReaderSize(strings.NewReader(input), 16) },
+				func() *Reader { return NewReaderSize(iotest.OneByteReader(strings.NewReader(input)), 1) },
+				func() *Reader { return NewReaderSize(iotest.HalfReader(strings.NewReader(input)), 20) },
+			} {
+				r := newReader()
+				r.SetLenient(lenient)
+				if s := readTokens(r); s != expected {
+					t.Errorf("TestReaderSize:%q:lenient=%t:expected=%s,s=%s", input, lenient, expected, s)
+				}
+			}
+		}
+	}
+
+	input := `[{"a" : [1, "` + long + `"] }, 2, "` + long + `"]`
+	r := NewReaderSize(iotest.OneByteReader(strings.NewReader(input)), 16)
+	if err := r.BeginArray(); err != nil {
+		t.Errorf("TestReaderSize:BeginArray:err=%s", err.Error())
+		return
+	}
+	if tokens, err := r.PeekN(7); err != nil {
+		t.Errorf("TestReaderSize:PeekN:err=%s", err.Error())
+	} else if len(tokens) != 7 || tokens[4].Value != long {
+		t.Errorf("TestReaderSize:PeekN=%v", tokens)
+	}
+	for _, expected := range []string{`{"a" : [1, "` + long + `"] }`, `2`, `"` + long + `"`} {
+		if raw, err := r.NextRawValue(); err != nil {
+			t.Errorf("TestReaderSize:NextRawValue:err=%s", err.Error())
+		} else if string(raw) != expected {
+			t.Errorf("TestReaderSize:NextRawValue:expected=%s,raw=%s", expected, raw)
+		}
+	}
+	if err := r.EndArray(); err != nil {
+		t.Errorf("TestReaderSize:EndArray:err=%s", err.Error())
+	}
+}
+
+func TestReaderSizeBytes(t *testing.T) {
+	r := NewReaderSize(strings.NewReader(`["a", "bc", "def"]`), 16)
+	if err := r.BeginArray(); err != nil {
+		t.Errorf("TestReaderSizeBytes:BeginArray:err=%s", err.Error())
+		return
+	}
+	var values [][]byte
+	for i := 0; i < 3; i++ {
+		value, err := r.NextStringBytes()
+		if err != nil {
+			t.Errorf("TestReaderSizeBytes:NextStringBytes:err=%s", err.Error())
+			return
+		}
+		values = append(values, append(value, 'x'))
+	}
+	if s := fmt.Sprintf("%s", values); s != "[ax bcx defx]" {
+		t.Errorf("TestReaderSizeBytes:values=%s", s)
+	}
+}
+
+func TestSkipValueUnchecked(t *testing.T) {
+	long := strings.Repeat("abcdefghij", 10)
+	for _, input := range []string{
+		`[1, "a", true, null, [], {}]`,
+		`[{"a": [1, {"b" : "x]}A"} ], "c":"é", "d": 1.5e3 , "e":true}, ["f\\\"]"], -0] [1]`,
+		`[["` + long + `", "\"` + long + `\\", "\\\\"], {"` + long + `": [[[["\\\"]"]]]]}, 2]`,
+		"[[1,\n2,\n3\n],\n{\"a\":\n[]}] [x]",
+	} {
+		var expected string
+		for _, unchecked := range []bool{false, true} {
+			for _, newReader := range []func() *Reader{
+				func() *Reader { return NewBytesReader([]byte(input)) },
+				func() *Reader { return NewReader(strings.NewReader(input)) },
+				func() *Reader { return NewReaderSize(iotest.OneByteReader(strings.NewReader(input)), 1) },
+			} {
+				r := newReader()
+				var buf bytes.Buffer
+				if err := r.BeginArray(); err != nil {
+					t.Errorf("TestSkipValueUnchecked:%q:BeginArray:err=%s", input, err.Error())
+					continue
+				}
+				for {
+					if hasNext, err := r.HasNext(); err != nil {
+						t.Errorf("TestSkipValueUnchecked:%q:HasNext:err=%s", input, err.Error())
+						break
+					} else if !hasNext {
+						break
+					}
+					var err error
+					if unchecked {
+						err = r.SkipValueUnchecked()
+					} else {
+						err = r.SkipValue()
+					}
+					if err != nil {
+						t.Errorf("TestSkipValueUnchecked:%q:unchecked=%t:err=%s", input, unchecked, err.Error())
+						break
+					}
+					fmt.Fprintf(&buf, "%s ", r.Path())
+				}
+				if err := r.EndArray(); err != nil {
+					t.Errorf("TestSkipValueUnchecked:%q:EndArray:err=%s", input, err.Error())
+					continue
+				}
+				buf.WriteString(readTokens(r))
+				if expected == "" {
+					expected = buf.String()
+				} else if buf.String() != expected {
+					t.Errorf("TestSkipValueUnchecked:%q:unchecked=%t:expected=%s,s=%s", input, unchecked, expected, buf.String())
+				}
+			}
+		}
+	}
+
+	for _, test := range []struct {
+		input    string
+		mode     DocumentMode
+		limits   Limits
+		expected string
+	}{
+		{`[[1, x, "]"]] 2`, ValueStream, Limits{}, `9"2" `},
+		{`[1, 2} 3`, ValueStream, Limits{}, `err=rgo: Invalid input at line 1, column 6 (offset 5): found "}", expected ']' path=$`},
+		{`{"a": [1]]`, ValueStream, Limits{}, `err=rgo: Invalid input at line 1, column 10 (offset 9): found "]", expected '}' path=$`},
+		{"[1,\n\"]\\\"", ValueStream, Limits{}, `err=rgo: Invalid input at line 2, column 5 (offset 8): found end of input, expected end of container path=$`},
+		{`[[[1]]]`, ValueStream, Limits{MaxDepth: 3}, ``},
+		{`[[[[1]]]]`, ValueStream, Limits{MaxDepth: 3}, `err=rgo: Nesting depth exceeds limit of 3 at offset 3 path=$`},
+		{"\x1e[1, \x1e[2]\n", JSONSequence, Limits{}, `err=rgo: Invalid input at line 1, column 6 (offset 5): found "\x1e", expected end of container path=$`},
+	} {
+		r := NewReaderSize(strings.NewReader(test.input), 16)
+		r.SetDocumentMode(test.mode)
+		r.SetLimits(test.limits)
+		var s string
+		if err := r.SkipValueUnchecked(); err != nil {
+			s = fmt.Sprintf("err=%s path=%s", err.Error(), r.Path())
+		} else {
+			s = readTokens(r)
+		}
+		if s != test.expected {
+			t.Errorf("TestSkipValueUnchecked:%q:expected=%s,s=%s", test.input, test.expected, s)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package rgo
+
+// A JSON value held in memory: *Object, *Array, String, Number, Bool or
+// Null.
+type Value interface {
+	// Return the token that starts the value: BEGIN_OBJECT, BEGIN_ARRAY,
+	// STRING, NUMBER, BOOLEAN or NULL.
+	Token() Token
+}
+
+// A JSON object, holding its members in the order in which they were
+// read or added, including any with duplicate names.
+type Object struct {
+	Members []Member
+}
+
+// A name and value in an Object.
+type Member struct {
+	Name  string
+	Value Value
+}
+
+// A JSON array.
+type Array struct {
+	Elements []Value
+}
+
+// A JSON string.
+type String string
+
+// A JSON true or false.
+type Bool bool
+
+// A JSON null.
+type Null struct{}
+
+func (o *Object) Token() Token { return BEGIN_OBJECT }
+func (a *Array) Token() Token  { return BEGIN_ARRAY }
+func (s String) Token() Token  { return STRING }
+func (n Number) Token() Token  { return NUMBER }
+func (b Bool) Token() Token    { return BOOLEAN }
+func (n Null) Token() Token    { return NULL }
+
+// Return the number of members, including any with duplicate names.
+func (o *Object) Len() int {
+	return len(o.Members)
+}
+
+// Return the names of the members in order, including duplicates.
+func (o *Object) Names() []string {
+	names := make([]string, len(o.Members))
+	for i, m := range o.Members {
+		names[i] = m.Name
+	}
+	return names
+}
+
+// Return the value of the last member with the given name, as
+// encoding/json would, or nil if there is none.
+func (o *Object) Get(name string) Value {
+	if i := o.lastIndex(name); i >= 0 {
+		return o.Members[i].Value
+	}
+	return nil
+}
+
+// Return the values of every member with the given name, in order.
+func (o *Object) GetAll(name string) []Value {
+	var values []Value
+	for _, m := range o.Members {
+		if m.Name == name {
+			values = append(values, m.Value)
+		}
+	}
+	return values
+}
+
+// Return true if there is a member with the given name.
+func (o *Object) Has(name string) bool {
+	return o.lastIndex(name) >= 0
+}
+
+// Set the value of the last member with the given name, or add a member
+// at the end if there is none.
+func (o *Object) Set(name string, value Value) {
+	if i := o.lastIndex(name); i >= 0 {
+		o.Members[i].Value = value
+	} else {
+		o.Members = append(o.Members, Member{Name: name, Value: value})
+	}
+}
+
+// Add a member at the end, even if there is already a member with the
+// same name.
+func (o *Object) Add(name string, value Value) {
+	o.Members = append(o.Members, Member{Name: name, Value: value})
+}
+
+// Remove every member with the given name, returning the number removed.
+func (o *Object) Delete(name string) int {
+	members := o.Members[:0]
+	for _, m := range o.Members {
+		if m.Name != name {
+			members = append(members, m)
+		}
+	}
+	n := len(o.Members) - len(members)
+	for i := len(members); i < len(o.Members); i++ {
+		o.Members[i] = Member{}
+	}
+	o.Members = members
+	return n
+}
+
+// Return the names that appear more than once, in the order of their
+// first duplicate.
+func (o *Object) Duplicates() []string {
+	var duplicates []string
+	seen := make(map[string]int, len(o.Members))
+	for _, m := range o.Members {
+		if seen[m.Name]++; seen[m.Name] == 2 {
+			duplicates = append(duplicates, m.Name)
+		}
+	}
+	return duplicates
+}
+
+func (o *Object) lastIndex(name string) int {
+	for i := len(o.Members) - 1; i >= 0; i-- {
+		if o.Members[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Return the number of elements.
+func (a *Array) Len() int {
+	return len(a.Elements)
+}
+
+// Return the element at index i, or nil if i is out of range.
+func (a *Array) Index(i int) Value {
+	if i < 0 || i >= len(a.Elements) {
+		return nil
+	}
+	return a.Elements[i]
+}
+
+// Replace the element at index i, returning IllegalArgument if i is out
+// of range.
+func (a *Array) Set(i int, value Value) error {
+	if i < 0 || i >= len(a.Elements) {
+		return IllegalArgument
+	}
+	a.Elements[i] = value
+	return nil
+}
+
+// Add elements at the end.
+func (a *Array) Append(values ...Value) {
+	a.Elements = append(a.Elements, values...)
+}
+
+// Remove the element at index i, returning IllegalArgument if i is out
+// of range.
+func (a *Array) Delete(i int) error {
+	if i < 0 || i >= len(a.Elements) {
+		return IllegalArgument
+	}
+	copy(a.Elements[i:], a.Elements[i+1:])
+	a.Elements[len(a.Elements)-1] = nil
+	a.Elements = a.Elements[:len(a.Elements)-1]
+	return nil
+}
+
+// Read the next value, including all nested values, into memory.  Numbers
+// are kept as their exact text, and the members of objects are kept in
+// order, including any with duplicate names.  If Limits.MaxDepth is not set,
+// values nested more than 10000 deep return a DepthLimitError.
+func ReadValue(r *Reader) (Value, error) {
+	token, err := r.Peek()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case BEGIN_OBJECT:
+		if err := r.checkRecursionDepth(); err != nil {
+			return nil, err
+		}
+		if err := r.BeginObject(); err != nil {
+			return nil, err
+		}
+		o := &Object{}
+		for {
+			if hasNext, err := r.HasNext(); err != nil {
+				return nil, err
+			} else if !hasNext {
+				break
+			}
+			name, err := r.NextName()
+			if err != nil {
+				return nil, err
+			}
+			value, err := ReadValue(r)
+			if err != nil {
+				return nil, err
+			}
+			o.Members = append(o.Members, Member{Name: name, Value: value})
+		}
+		if err := r.EndObject(); err != nil {
+			return nil, err
+		}
+		return o, nil
+	case BEGIN_ARRAY:
+		if err := r.checkRecursionDepth(); err != nil {
+			return nil, err
+		}
+		if err := r.BeginArray(); err != nil {
+			return nil, err
+		}
+		a := &Array{}
+		for {
+			if hasNext, err := r.HasNext(); err != nil {
+				return nil, err
+			} else if !hasNext {
+				break
+			}
+			value, err := ReadValue(r)
+			if err != nil {
+				return nil, err
+			}
+			a.Elements = append(a.Elements, value)
+		}
+		if err := r.EndArray(); err != nil {
+			return nil, err
+		}
+		return a, nil
+	case STRING:
+		s, err := r.NextString()
+		if err != nil {
+			return nil, err
+		}
+		return String(s), nil
+	case NUMBER:
+		return r.NextNumber()
+	case BOOLEAN:
+		b, err := r.NextBoolean()
+		if err != nil {
+			return nil, err
+		}
+		return Bool(b), nil
+	case NULL:
+		if err := r.NextNull(); err != nil {
+			return nil, err
+		}
+		return Null{}, nil
+	default:
+		return nil, IllegalState
+	}
+}
+
+// Write v, including all nested values.  A nil Value, *Object or *Array
+// is written as null.  Returns IllegalArgument if v is not one of the
+// types of Value defined by this package.
+func WriteValue(w *Writer, v Value) error {
+	switch v := v.(type) {
+	case *Object:
+		if v == nil {
+			return w.NullValue()
+		}
+		if err := w.BeginObject(); err != nil {
+			return err
+		}
+		for _, m := range v.Members {
+			if err := w.Name(m.Name); err != nil {
+				return err
+			}
+			if err := WriteValue(w, m.Value); err != nil {
+				return err
+			}
+		}
+		return w.EndObject()
+	case *Array:
+		if v == nil {
+			return w.NullValue()
+		}
+		if err := w.BeginArray(); err != nil {
+			return err
+		}
+		for _, e := range v.Elements {
+			if err := WriteValue(w, e); err != nil {
+				return err
+			}
+		}
+		return w.EndArray()
+	case String:
+		return w.StringValue(string(v))
+	case Number:
+		return w.NumberValue(v)
+	case Bool:
+		return w.BoolValue(bool(v))
+	case Null, nil:
+		return w.NullValue()
+	default:
+		return IllegalArgument
+	}
+}
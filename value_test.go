@@ -0,0 +1,143 @@
+package rgo
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadWriteValue(t *testing.T) {
+	for _, input := range []string{
+		`{"b":1,"a":[true,false,null,"x\n"],"b":{},"c":[]}`,
+		`[12345678901234567890.123456789e-100,-0,1E+2]`,
+		`"é𝄞"`,
+		`null`,
+	} {
+		v, err := ReadValue(NewReader(strings.NewReader(input)))
+		if err != nil {
+			t.Errorf("TestReadWriteValue:%s:ReadValue:err=%s", input, err.Error())
+			continue
+		}
+		var buf bytes.Buffer
+		if err := WriteValue(NewWriter(&buf), v); err != nil {
+			t.Errorf("TestReadWriteValue:%s:WriteValue:err=%s", input, err.Error())
+		}
+		if buf.String() != input {
+			t.Errorf("TestReadWriteValue:%s:s=%s", input, buf.String())
+		}
+	}
+
+	r := NewReader(strings.NewReader(`[1, {"a": x}]`))
+	if _, err := ReadValue(r); err == nil {
+		t.Errorf("TestReadWriteValue:ReadValue:err=nil")
+	}
+	r = NewReader(strings.NewReader(`[]`))
+	if err := r.BeginArray(); err != nil {
+		t.Errorf("TestReadWriteValue:BeginArray:err=%s", err.Error())
+	}
+	if _, err := ReadValue(r); err != IllegalState {
+		t.Errorf("TestReadWriteValue:ReadValue:err=%v", err)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := WriteValue(w, &Array{Elements: []Value{nil, (*Object)(nil), String("a"), Number("1.50")}}); err != nil {
+		t.Errorf("TestReadWriteValue:WriteValue:err=%s", err.Error())
+	} else if s := buf.String(); s != `[null,null,"a",1.50]` {
+		t.Errorf("TestReadWriteValue:WriteValue=%s", s)
+	}
+	if err := WriteValue(NewWriter(&buf), Number("x")); err != IllegalArgument {
+		t.Errorf("TestReadWriteValue:WriteValue:err=%v", err)
+	}
+}
+
+func TestReadValueDepth(t *testing.T) {
+	for _, test := range []struct {
+		depth  int
+		limits Limits
+		offset int64
+	}{
+		{10000, Limits{}, -1},
+		{10001, Limits{}, 10000},
+		{1000000, Limits{}, 10000},
+		{20000, Limits{MaxDepth: 20000}, -1},
+		{20, Limits{MaxDepth: 10}, 10},
+	} {
+		input := strings.Repeat("[", test.depth) + strings.Repeat("]", test.depth)
+		for _, r := range []*Reader{NewReader(strings.NewReader(input)), NewBytesReader([]byte(input))} {
+			r.SetLimits(test.limits)
+			_, err := ReadValue(r)
+			var depthLimitError *DepthLimitError
+			if test.offset < 0 {
+				if err != nil {
+					t.Errorf("TestReadValueDepth:%d:err=%s", test.depth, err.Error())
+				}
+			} else if !errors.As(err, &depthLimitError) || depthLimitError.Offset != test.offset {
+				t.Errorf("TestReadValueDepth:%d:err=%v", test.depth, err)
+			}
+		}
+	}
+}
+
+func TestObject(t *testing.T) {
+	v, err := ReadValue(NewReader(strings.NewReader(`{"a": 1, "b": 2, "a": 3, "c": {"d": [4, 5]}, "b": 6, "a": 7}`)))
+	if err != nil {
+		t.Errorf("TestObject:ReadValue:err=%s", err.Error())
+		return
+	}
+	o, ok := v.(*Object)
+	if !ok || o.Token() != BEGIN_OBJECT {
+		t.Errorf("TestObject:ReadValue=%#v", v)
+		return
+	}
+	if n := o.Len(); n != 6 {
+		t.Errorf("TestObject:Len=%d", n)
+	}
+	if names := o.Names(); !reflect.DeepEqual(names, []string{"a", "b", "a", "c", "b", "a"}) {
+		t.Errorf("TestObject:Names=%v", names)
+	}
+	if duplicates := o.Duplicates(); !reflect.DeepEqual(duplicates, []string{"a", "b"}) {
+		t.Errorf("TestObject:Duplicates=%v", duplicates)
+	}
+	if value := o.Get("a"); value != Number("7") {
+		t.Errorf("TestObject:Get=%v", value)
+	}
+	if values := o.GetAll("a"); !reflect.DeepEqual(values, []Value{Number("1"), Number("3"), Number("7")}) {
+		t.Errorf("TestObject:GetAll=%v", values)
+	}
+	if value := o.Get("x"); value != nil || o.Has("x") || !o.Has("c") {
+		t.Errorf("TestObject:Get=%v", value)
+	}
+	a := o.Get("c").(*Object).Get("d").(*Array)
+	if a.Len() != 2 || a.Index(1) != Number("5") || a.Index(2) != nil || a.Index(-1) != nil {
+		t.Errorf("TestObject:Array=%v", a.Elements)
+	}
+
+	o.Set("b", Bool(true))
+	o.Set("e", String("f"))
+	o.Add("e", Null{})
+	if n := o.Delete("a"); n != 3 {
+		t.Errorf("TestObject:Delete=%d", n)
+	}
+	if err := a.Set(0, String("x")); err != nil {
+		t.Errorf("TestObject:Set:err=%s", err.Error())
+	}
+	if err := a.Set(2, String("x")); err != IllegalArgument {
+		t.Errorf("TestObject:Set:err=%v", err)
+	}
+	a.Append(Number("6"), Number("7"))
+	if err := a.Delete(1); err != nil {
+		t.Errorf("TestObject:Delete:err=%s", err.Error())
+	}
+	if err := a.Delete(3); err != IllegalArgument {
+		t.Errorf("TestObject:Delete:err=%v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteValue(NewWriter(&buf), o); err != nil {
+		t.Errorf("TestObject:WriteValue:err=%s", err.Error())
+	} else if s := buf.String(); s != `{"b":2,"c":{"d":["x",6,7]},"b":true,"e":"f","e":null}` {
+		t.Errorf("TestObject:WriteValue=%s", s)
+	}
+}
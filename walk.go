@@ -0,0 +1,128 @@
+package rgo
+
+import (
+	"errors"
+)
+
+// Receives the tokens of a value read by Walk.  If a method returns an
+// error, Walk stops and returns it, except for SkipSubtree and StopWalk.
+type Handler interface {
+	// Called at the beginning of an object.  Returning SkipSubtree skips
+	// the object, and OnEndObject is not called for it.
+	OnBeginObject() error
+	// Called at the end of an object.
+	OnEndObject() error
+	// Called at the beginning of an array.  Returning SkipSubtree skips
+	// the array, and OnEndArray is not called for it.
+	OnBeginArray() error
+	// Called at the end of an array.
+	OnEndArray() error
+	// Called with the name of each member of an object.  Returning
+	// SkipSubtree skips the value of the member.
+	OnName(name string) error
+	OnString(value string) error
+	OnNumber(value Number) error
+	OnBool(value bool) error
+	OnNull() error
+}
+
+var (
+	// Returned by a Handler to skip an array, an object, or the value of
+	// a member of an object.  Returned by any other method, it is
+	// ignored.
+	SkipSubtree = errors.New("rgo: Skip subtree")
+	// Returned by a Handler to stop Walk, which then returns nil.  The
+	// Reader is left after the last token passed to the Handler.
+	StopWalk = errors.New("rgo: Stop walk")
+)
+
+// Read the next value, calling the methods of h for each of its tokens,
+// including those of every nested value.  Returns IllegalState if the
+// next token does not start a value.
+func Walk(r *Reader, h Handler) error {
+	switch token, err := r.Peek(); {
+	case err != nil:
+		return err
+	case token == NAME || token == END_ARRAY || token == END_OBJECT || token == END_DOCUMENT:
+		return IllegalState
+	}
+	depth := 0
+	for {
+		token, err := r.Peek()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case BEGIN_OBJECT:
+			switch err = h.OnBeginObject(); err {
+			case SkipSubtree:
+				err = r.SkipValue()
+			case nil, StopWalk:
+				// Consumed even when stopping, as it has been passed
+				// to the Handler.
+				depth++
+				if err2 := r.BeginObject(); err2 != nil {
+					err = err2
+				}
+			}
+		case END_OBJECT:
+			depth--
+			if err = r.EndObject(); err == nil {
+				err = h.OnEndObject()
+			}
+		case BEGIN_ARRAY:
+			switch err = h.OnBeginArray(); err {
+			case SkipSubtree:
+				err = r.SkipValue()
+			case nil, StopWalk:
+				depth++
+				if err2 := r.BeginArray(); err2 != nil {
+					err = err2
+				}
+			}
+		case END_ARRAY:
+			depth--
+			if err = r.EndArray(); err == nil {
+				err = h.OnEndArray()
+			}
+		case NAME:
+			var name string
+			if name, err = r.NextName(); err == nil {
+				if err = h.OnName(name); err == SkipSubtree {
+					err = r.SkipValue()
+				}
+			}
+		case STRING:
+			var value string
+			if value, err = r.NextString(); err == nil {
+				err = h.OnString(value)
+			}
+		case NUMBER:
+			var value Number
+			if value, err = r.NextNumber(); err == nil {
+				err = h.OnNumber(value)
+			}
+		case BOOLEAN:
+			var value bool
+			if value, err = r.NextBoolean(); err == nil {
+				err = h.OnBool(value)
+			}
+		case NULL:
+			if err = r.NextNull(); err == nil {
+				err = h.OnNull()
+			}
+		default:
+			panic("rgo: Internal error")
+		}
+		switch err {
+		case nil, SkipSubtree:
+		case StopWalk:
+			return nil
+		default:
+			return err
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package rgo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// A Handler that records the tokens it receives.
+type recordingHandler struct {
+	buf bytes.Buffer
+	// The names whose values are skipped.
+	skipName string
+	// Skip every array.
+	skipArrays bool
+	// The string at which to stop.
+	stopString string
+	// Stop at the first object.
+	stopObject bool
+	err        error
+}
+
+func (h *recordingHandler) OnBeginObject() error {
+	h.buf.WriteString("{ ")
+	if h.stopObject {
+		return StopWalk
+	}
+	return nil
+}
+
+func (h *recordingHandler) OnEndObject() error {
+	h.buf.WriteString("} ")
+	return nil
+}
+
+func (h *recordingHandler) OnBeginArray() error {
+	if h.skipArrays {
+		h.buf.WriteString("[...] ")
+		return SkipSubtree
+	}
+	h.buf.WriteString("[ ")
+	return nil
+}
+
+func (h *recordingHandler) OnEndArray() error {
+	h.buf.WriteString("] ")
+	return nil
+}
+
+func (h *recordingHandler) OnName(name string) error {
+	fmt.Fprintf(&h.buf, "%q: ", name)
+	if name == h.skipName {
+		return SkipSubtree
+	}
+	return nil
+}
+
+func (h *recordingHandler) OnString(value string) error {
+	fmt.Fprintf(&h.buf, "%q ", value)
+	if value == h.stopString {
+		return StopWalk
+	}
+	return nil
+}
+
+func (h *recordingHandler) OnNumber(value Number) error {
+	fmt.Fprintf(&h.buf, "%s ", value)
+	return h.err
+}
+
+func (h *recordingHandler) OnBool(value bool) error {
+	fmt.Fprintf(&h.buf, "%t ", value)
+	return nil
+}
+
+func (h *recordingHandler) OnNull() error {
+	h.buf.WriteString("null ")
+	return nil
+}
+
+func TestWalk(t *testing.T) {
+	handlerErr := errors.New("handler error")
+	for _, test := range []struct {
+		input    string
+		handler  recordingHandler
+		expected string
+		// The tokens left after Walk, or after an error.
+		rest string
+	}{
+		{`{"a": [1, "b", true, null, {}], "c": -1.5e3} 2`, recordingHandler{}, `{ "a": [ 1 "b" true null { } ] "c": -1.5e3 } `, `9"2" `},
+		{`"a" "b"`, recordingHandler{}, `"a" `, `10"b" `},
+		{`{"a": [1, {"b": 2}], "c": 3}`, recordingHandler{skipName: "a"}, `{ "a": "c": 3 } `, ``},
+		{`[[1], {"a": [2]}, 3]`, recordingHandler{skipArrays: true}, `[...] `, ``},
+		{`{"a": [1], "b": 2}`, recordingHandler{skipArrays: true}, `{ "a": [...] "b": 2 } `, ``},
+		{`["a", "stop", "b"]`, recordingHandler{stopString: "stop"}, `[ "a" "stop" `, `10"b" ] `},
+		{`[1, 2]`, recordingHandler{err: handlerErr}, `[ 1 err `, `9"2" ] `},
+		{`[1,{"a":[2]},3]`, recordingHandler{stopObject: true}, `[ 1 { `, `"a": [ 9"2" ] } 9"3" ] `},
+		{`[1, x]`, recordingHandler{}, `[ 1 err=rgo: Invalid input at line 1, column 5 (offset 4): found "x", expected value`, `err=rgo: Invalid input at line 1, column 5 (offset 4): found "x", expected value path=$[1]`},
+	} {
+		r := NewReader(bytes.NewBufferString(test.input))
+		h := test.handler
+		if err := Walk(r, &h); err == handlerErr {
+			h.buf.WriteString("err ")
+		} else if err != nil {
+			fmt.Fprintf(&h.buf, "err=%s", err.Error())
+		}
+		if s := h.buf.String(); s != test.expected {
+			t.Errorf("TestWalk:%s:expected=%s,s=%s", test.input, test.expected, s)
+		}
+		if rest := readTokens(r); rest != test.rest {
+			t.Errorf("TestWalk:%s:expected=%s,rest=%s", test.input, test.rest, rest)
+		}
+	}
+
+	for _, input := range []string{``, `[]`, `{"a":1}`} {
+		r := NewReader(bytes.NewBufferString(input))
+		if input == `[]` {
+			if err := r.BeginArray(); err != nil {
+				t.Errorf("TestWalk:%s:BeginArray:err=%s", input, err.Error())
+			}
+		} else if input == `{"a":1}` {
+			if err := r.BeginObject(); err != nil {
+				t.Errorf("TestWalk:%s:BeginObject:err=%s", input, err.Error())
+			}
+		}
+		if err := Walk(r, &recordingHandler{}); err != IllegalState {
+			t.Errorf("TestWalk:%s:err=%v", input, err)
+		}
+	}
+}